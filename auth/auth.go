@@ -0,0 +1,146 @@
+// Package auth authenticates WebSocket upgrade requests through a
+// pluggable Authenticator, and mints the short-lived tokens and signed
+// cookies clients present to satisfy it.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode selects which Authenticator a Service uses to gate connections.
+type Mode string
+
+const (
+	// ModeNone disables authentication; every request is allowed.
+	ModeNone         Mode = "none"
+	ModeSharedSecret Mode = "shared-secret"
+	ModeJWT          Mode = "jwt"
+	ModeBasic        Mode = "basic"
+)
+
+// Config configures a Service. It is built from config.Config by main.
+type Config struct {
+	Mode     Mode
+	Secret   string
+	TTL      time.Duration
+	Username string
+	Password string
+}
+
+// Service authenticates connections per Config and mints the tokens and
+// cookies clients use to satisfy it.
+type Service struct {
+	cfg           Config
+	authenticator Authenticator
+}
+
+// NewService builds a Service for cfg. A Mode of ModeNone (or the zero
+// value) returns a Service whose Enabled method reports false.
+func NewService(cfg Config) *Service {
+	var authenticator Authenticator
+	switch cfg.Mode {
+	case ModeSharedSecret:
+		authenticator = SharedSecretAuthenticator{Secret: cfg.Secret}
+	case ModeJWT:
+		authenticator = JWTAuthenticator{Secret: []byte(cfg.Secret)}
+	case ModeBasic:
+		authenticator = BasicAuthAuthenticator{Username: cfg.Username, Password: cfg.Password}
+	}
+	return &Service{cfg: cfg, authenticator: authenticator}
+}
+
+// Enabled reports whether connections must be authenticated.
+func (s *Service) Enabled() bool {
+	return s.authenticator != nil
+}
+
+// Authenticate validates r against the configured Authenticator. When
+// authentication is disabled it always succeeds with an empty subject. A
+// valid "session" cookie set by SignCookie is accepted regardless of
+// Mode, satisfying the static file handler's signed-cookie pairing flow.
+// So is a token minted by MintToken: /auth always mints a JWT, regardless
+// of Mode, so it must be accepted regardless of Mode too, or the
+// shared-secret and basic flows could never complete a token login.
+func (s *Service) Authenticate(r *http.Request) (string, error) {
+	if s.authenticator == nil {
+		return "", nil
+	}
+	if cookie, err := r.Cookie("session"); err == nil {
+		if subject, ok := s.VerifyCookie(cookie.Value); ok {
+			return subject, nil
+		}
+	}
+	if token := tokenFromRequest(r); token != "" {
+		if subject, err := verifyJWT(token, []byte(s.cfg.Secret)); err == nil {
+			return subject, nil
+		}
+	}
+	return s.authenticator.Authenticate(r)
+}
+
+// Login authenticates a login request (HTTP Basic credentials, or a
+// shared secret presented as a token) independent of Mode, and is used by
+// the /auth endpoint to decide who to mint a token or cookie for.
+func (s *Service) Login(r *http.Request) (string, error) {
+	if s.cfg.Username != "" {
+		return BasicAuthAuthenticator{Username: s.cfg.Username, Password: s.cfg.Password}.Authenticate(r)
+	}
+	return SharedSecretAuthenticator{Secret: s.cfg.Secret}.Authenticate(r)
+}
+
+// MintToken creates a short-lived JWT for subject, valid for the
+// Service's configured TTL.
+func (s *Service) MintToken(subject string) (string, error) {
+	return signJWT([]byte(s.cfg.Secret), subject, s.cfg.TTL)
+}
+
+// TTL returns the configured token/cookie lifetime.
+func (s *Service) TTL() time.Duration {
+	return s.cfg.TTL
+}
+
+// SignCookie produces an HMAC-signed "subject.exp.signature" value
+// suitable for a session cookie, as set by the static file handler after
+// a successful login. exp is the Service's configured TTL out from now,
+// the same expiry semantics as MintToken.
+func (s *Service) SignCookie(subject string) string {
+	payload := subject + "." + strconv.FormatInt(time.Now().Add(s.cfg.TTL).Unix(), 10)
+	return payload + "." + s.sign(payload)
+}
+
+// VerifyCookie checks a cookie value produced by SignCookie and returns
+// the subject it was signed for, rejecting it if the signature doesn't
+// match or it has passed its embedded expiry.
+func (s *Service) VerifyCookie(value string) (string, bool) {
+	idx := strings.LastIndex(value, ".")
+	if idx < 0 {
+		return "", false
+	}
+	payload, signature := value[:idx], value[idx+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return "", false
+	}
+
+	expIdx := strings.LastIndex(payload, ".")
+	if expIdx < 0 {
+		return "", false
+	}
+	subject, expStr := payload[:expIdx], payload[expIdx+1:]
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || time.Now().After(time.Unix(exp, 0)) {
+		return "", false
+	}
+	return subject, true
+}
+
+func (s *Service) sign(subject string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.Secret))
+	mac.Write([]byte(subject))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignCookieRoundTrip(t *testing.T) {
+	s := NewService(Config{Mode: ModeSharedSecret, Secret: "s3cr3t", TTL: time.Hour})
+
+	cookie := s.SignCookie("alice")
+	subject, ok := s.VerifyCookie(cookie)
+	if !ok || subject != "alice" {
+		t.Fatalf("VerifyCookie = %q, %v, want alice, true", subject, ok)
+	}
+}
+
+func TestVerifyCookieRejectsExpired(t *testing.T) {
+	s := NewService(Config{Mode: ModeSharedSecret, Secret: "s3cr3t", TTL: -time.Hour})
+
+	cookie := s.SignCookie("alice")
+	if _, ok := s.VerifyCookie(cookie); ok {
+		t.Error("VerifyCookie accepted a cookie past its embedded expiry")
+	}
+}
+
+func TestVerifyCookieRejectsTamperedSignature(t *testing.T) {
+	s := NewService(Config{Mode: ModeSharedSecret, Secret: "s3cr3t", TTL: time.Hour})
+
+	cookie := s.SignCookie("alice") + "x"
+	if _, ok := s.VerifyCookie(cookie); ok {
+		t.Error("VerifyCookie accepted a tampered cookie")
+	}
+}
+
+func TestVerifyCookieRejectsWrongSecret(t *testing.T) {
+	s := NewService(Config{Mode: ModeSharedSecret, Secret: "s3cr3t", TTL: time.Hour})
+	other := NewService(Config{Mode: ModeSharedSecret, Secret: "different", TTL: time.Hour})
+
+	cookie := s.SignCookie("alice")
+	if _, ok := other.VerifyCookie(cookie); ok {
+		t.Error("VerifyCookie accepted a cookie signed with a different secret")
+	}
+}
+
+func TestAuthenticateAcceptsMintedTokenRegardlessOfMode(t *testing.T) {
+	for _, mode := range []Mode{ModeSharedSecret, ModeJWT, ModeBasic} {
+		s := NewService(Config{Mode: mode, Secret: "s3cr3t", Username: "u", Password: "p", TTL: time.Hour})
+
+		token, err := s.MintToken("alice")
+		if err != nil {
+			t.Fatalf("MintToken: %v", err)
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/ws/room?token="+token, nil)
+		subject, err := s.Authenticate(r)
+		if err != nil || subject != "alice" {
+			t.Errorf("mode %s: Authenticate(minted token) = %q, %v, want alice, nil", mode, subject, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingToken       = errors.New("auth: missing token")
+	ErrInvalidToken       = errors.New("auth: invalid token")
+	ErrTokenExpired       = errors.New("auth: token expired")
+	ErrMissingCredentials = errors.New("auth: missing credentials")
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+// Authenticator validates the credentials carried by an HTTP request
+// (including a WebSocket upgrade request) and, on success, returns a
+// subject identifier used for rate limiting and session cookies.
+type Authenticator interface {
+	Authenticate(r *http.Request) (subject string, err error)
+}
+
+// tokenFromRequest extracts a bearer token from, in order: the "token"
+// query parameter, a "keyboard.auth.<token>" entry in the
+// Sec-WebSocket-Protocol header (so a browser's WebSocket constructor can
+// carry it without custom headers), or a "session" cookie.
+func tokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	for _, proto := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		proto = strings.TrimSpace(proto)
+		if rest, ok := strings.CutPrefix(proto, "keyboard.auth."); ok {
+			return rest
+		}
+	}
+	if cookie, err := r.Cookie("session"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// SharedSecretAuthenticator accepts a request whose token matches Secret
+// exactly.
+type SharedSecretAuthenticator struct {
+	Secret string
+}
+
+func (a SharedSecretAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token := tokenFromRequest(r)
+	if token == "" {
+		return "", ErrMissingToken
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.Secret)) != 1 {
+		return "", ErrInvalidToken
+	}
+	return token, nil
+}
+
+// JWTAuthenticator accepts a request carrying a token minted by
+// Service.MintToken, signed with Secret.
+type JWTAuthenticator struct {
+	Secret []byte
+}
+
+func (a JWTAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token := tokenFromRequest(r)
+	if token == "" {
+		return "", ErrMissingToken
+	}
+	return verifyJWT(token, a.Secret)
+}
+
+// BasicAuthAuthenticator accepts a request carrying HTTP Basic
+// credentials matching Username and Password.
+type BasicAuthAuthenticator struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuthAuthenticator) Authenticate(r *http.Request) (string, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", ErrMissingCredentials
+	}
+	if subtle.ConstantTimeCompare([]byte(username), []byte(a.Username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(a.Password)) != 1 {
+		return "", ErrInvalidCredentials
+	}
+	return username, nil
+}
+
+// StatusCode maps an authentication error to the HTTP status a handler
+// should reject the request with: 401 when no credentials were presented
+// at all, 403 when they were presented but rejected.
+func StatusCode(err error) int {
+	switch err {
+	case ErrMissingToken, ErrMissingCredentials:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusForbidden
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a keyed token bucket, used to cap how often an
+// authenticated client can drive input.PressKey. Callers key it by
+// whatever identifies a distinct client; the server keys it by member
+// ID rather than auth subject, since auth subjects aren't guaranteed to
+// be distinct per connection (e.g. a shared secret is the same for
+// every client in shared-secret mode).
+type RateLimiter struct {
+	ratePerSecond float64
+	burst         float64
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// idleBucketTTL is how long a bucket may go untouched before the
+// sweeper reclaims it. The server keys by member ID, minted fresh for
+// each new session (only a resumed session reuses one), so without a
+// sweep nearly every connect a long-running deployment ever sees would
+// leak a bucket forever.
+const idleBucketTTL = 10 * time.Minute
+
+// sweepInterval is how often the sweeper goroutine scans for idle
+// buckets.
+const sweepInterval = time.Minute
+
+// NewRateLimiter creates a RateLimiter allowing ratePerSecond sustained
+// events per key, with bursts up to burst events. It starts a
+// background goroutine that evicts buckets idle for longer than
+// idleBucketTTL, so memory use tracks concurrent clients rather than
+// every key ever seen.
+func NewRateLimiter(ratePerSecond float64, burst int) *RateLimiter {
+	l := &RateLimiter{
+		ratePerSecond: ratePerSecond,
+		burst:         float64(burst),
+		buckets:       make(map[string]*bucket),
+	}
+	go l.sweep()
+	return l
+}
+
+func (l *RateLimiter) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		l.evictIdle(now)
+	}
+}
+
+// evictIdle removes every bucket untouched since before now minus
+// idleBucketTTL. Split out from sweep so tests can drive eviction
+// without waiting on the ticker.
+func (l *RateLimiter) evictIdle(now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Allow reports whether key may perform another event now, consuming
+// one token from its bucket if so.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens += elapsed * l.ratePerSecond
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterKeysIndependently(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+
+	if !l.Allow("member-a") {
+		t.Fatal("first event for member-a should be allowed")
+	}
+	if l.Allow("member-a") {
+		t.Fatal("second immediate event for member-a should be throttled")
+	}
+	if !l.Allow("member-b") {
+		t.Error("member-b should have its own bucket, unaffected by member-a")
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	l.Allow("member-a")
+
+	if len(l.buckets) != 1 {
+		t.Fatalf("len(buckets) = %d, want 1 before eviction", len(l.buckets))
+	}
+
+	l.evictIdle(time.Now().Add(idleBucketTTL + time.Minute))
+
+	if len(l.buckets) != 0 {
+		t.Errorf("len(buckets) = %d, want 0 after evicting an idle bucket", len(l.buckets))
+	}
+}
+
+func TestRateLimiterEvictIdleKeepsRecentBuckets(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	l.Allow("member-a")
+
+	l.evictIdle(time.Now())
+
+	if len(l.buckets) != 1 {
+		t.Errorf("len(buckets) = %d, want 1 — a just-touched bucket shouldn't be evicted", len(l.buckets))
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds runtime configuration loaded from the environment.
+type Config struct {
+	Port string
+
+	// MacroFile is the path macros are persisted to and loaded from on
+	// startup.
+	MacroFile string
+	// KeymapFile optionally points to a JSON file remapping input strings
+	// to platform keycodes. Empty disables custom remapping.
+	KeymapFile string
+
+	// AuthMode selects how WebSocket connections are authenticated:
+	// "none", "shared-secret", "jwt", or "basic".
+	AuthMode string
+	// AuthSecret is the shared secret (shared-secret mode) or signing
+	// key (jwt mode) used to validate connections.
+	AuthSecret string
+	// AuthUsername and AuthPassword are the credentials checked in basic
+	// mode, and the login credentials accepted by /auth in jwt mode.
+	AuthUsername string
+	AuthPassword string
+	// SessionTTL is how long a token minted by /auth, or a cookie set by
+	// the static file handler, remains valid.
+	SessionTTL time.Duration
+
+	// RateLimitPerSecond and RateLimitBurst cap how often an
+	// authenticated client can drive key presses.
+	RateLimitPerSecond float64
+	RateLimitBurst     int
+
+	// MouseEnabled turns on the pointer/scroll input subsystem
+	// independently of keyboard control. Disabled by default since it
+	// grants more reach over the host than a numeric keypad.
+	MouseEnabled bool
+
+	// EventLogSize is how many outbound messages each room retains for
+	// replay to a reconnecting client.
+	EventLogSize int
+	// ResumeTTL is how long a disconnected session remains resumable
+	// before its session entry is swept.
+	ResumeTTL time.Duration
+
+	// ModeratorToken, presented as the "moderator_token" query parameter
+	// on a WebSocket upgrade, grants the connecting member the Moderator
+	// role. Empty disables moderator assignment entirely.
+	ModeratorToken string
+	// MaxViewers caps how many members may be connected to a room at
+	// once. Zero or negative means unlimited.
+	MaxViewers int
+	// ControllerPolicy selects how control of a room is awarded as
+	// members join and leave: "first-come" or "moderator-assigned".
+	ControllerPolicy string
+}
+
+// Load builds a Config from environment variables, falling back to
+// sensible defaults for local development.
+func Load() *Config {
+	return &Config{
+		Port:       getEnv("PORT", "8080"),
+		MacroFile:  getEnv("MACRO_FILE", "macros.json"),
+		KeymapFile: getEnv("KEYMAP_FILE", ""),
+
+		AuthMode:     getEnv("AUTH_MODE", "none"),
+		AuthSecret:   getEnv("AUTH_SECRET", ""),
+		AuthUsername: getEnv("AUTH_USERNAME", ""),
+		AuthPassword: getEnv("AUTH_PASSWORD", ""),
+		SessionTTL:   getEnvDuration("SESSION_TTL", 24*time.Hour),
+
+		RateLimitPerSecond: getEnvFloat("RATE_LIMIT_PER_SECOND", 20),
+		RateLimitBurst:     getEnvInt("RATE_LIMIT_BURST", 40),
+
+		MouseEnabled: getEnvBool("MOUSE_ENABLED", false),
+
+		EventLogSize: getEnvInt("EVENT_LOG_SIZE", 64),
+		ResumeTTL:    getEnvDuration("RESUME_TTL", 2*time.Minute),
+
+		ModeratorToken:   getEnv("MODERATOR_TOKEN", ""),
+		MaxViewers:       getEnvInt("MAX_VIEWERS", 8),
+		ControllerPolicy: getEnv("CONTROLLER_POLICY", "first-come"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
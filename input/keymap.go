@@ -0,0 +1,35 @@
+package input
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Keymap maps a logical input string (lowercased) to the platform keycode
+// robotgo expects.
+type Keymap map[string]string
+
+func defaultKeymap() Keymap {
+	return Keymap{
+		"0": "kp_0", "1": "kp_1", "2": "kp_2", "3": "kp_3", "4": "kp_4",
+		"5": "kp_5", "6": "kp_6", "7": "kp_7", "8": "kp_8", "9": "kp_9",
+		"*": "kp_multiply", "+": "kp_add", "-": "kp_subtract", ".": "kp_decimal", "/": "kp_divide",
+		"enter": "kp_enter", "backspace": "backspace", "escape": "escape",
+	}
+}
+
+// LoadKeymap reads a JSON file of the form {"input string": "keycode"}
+// from path. It does not merge with the default keymap; callers combine
+// the result themselves.
+func LoadKeymap(path string) (Keymap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keymap Keymap
+	if err := json.Unmarshal(data, &keymap); err != nil {
+		return nil, err
+	}
+	return keymap, nil
+}
@@ -0,0 +1,138 @@
+package input
+
+import (
+	"github.com/go-vgo/robotgo"
+)
+
+// Point is a 2D coordinate, used for drag start/end points and screen
+// size reporting. Display DPI is not reported.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// MouseEventKind selects how a dispatched MouseEvent is applied to the
+// host.
+type MouseEventKind string
+
+const (
+	MouseMove   MouseEventKind = "move"
+	MouseClick  MouseEventKind = "click"
+	MouseScroll MouseEventKind = "scroll"
+	MouseDrag   MouseEventKind = "drag"
+)
+
+// MouseEvent is a single pointer action as received from a client,
+// independent of the wire format it arrived in. Which fields are
+// meaningful depends on Kind.
+type MouseEvent struct {
+	Kind MouseEventKind
+
+	X, Y     int
+	Absolute bool
+
+	Button string
+	Double bool
+
+	DX, DY int
+
+	From, To Point
+}
+
+// MouseSimulator drives the host's pointer and scroll wheel, parallel to
+// KeySimulator for the keyboard. Implementations translate absolute or
+// relative pixel coordinates into OS-level input; normalized coordinates
+// are converted by the client, using ScreenSize.
+type MouseSimulator interface {
+	// Move positions the pointer at (x, y). When absolute is false, x
+	// and y are a relative offset from the pointer's current position
+	// instead of a screen coordinate.
+	Move(x, y int, absolute bool) error
+	// Click presses and releases button ("left", "right", or "middle").
+	// When double is true, it clicks twice in quick succession.
+	Click(button string, double bool) error
+	// ScrollWheel scrolls the wheel by dx horizontally and dy vertically.
+	ScrollWheel(dx, dy int) error
+	// Drag moves the pointer to from, presses the left button, moves to
+	// to, then releases it.
+	Drag(from, to Point) error
+	// ScreenSize reports the primary display's resolution, so a client
+	// can translate normalized coordinates into absolute ones.
+	ScreenSize() (Point, error)
+	// Dispatch applies a MouseEvent built from any wire format.
+	Dispatch(event MouseEvent) error
+}
+
+type mouseSimulator struct{}
+
+// NewMouseSimulator returns a MouseSimulator backed by robotgo.
+func NewMouseSimulator() MouseSimulator {
+	return &mouseSimulator{}
+}
+
+func (m *mouseSimulator) Move(x, y int, absolute bool) error {
+	return m.withPlatformSupport(func() error {
+		if absolute {
+			robotgo.Move(x, y)
+			return nil
+		}
+		cx, cy := robotgo.Location()
+		robotgo.Move(cx+x, cy+y)
+		return nil
+	})
+}
+
+func (m *mouseSimulator) Click(button string, double bool) error {
+	return m.withPlatformSupport(func() error {
+		robotgo.Click(mapButton(button), double)
+		return nil
+	})
+}
+
+func (m *mouseSimulator) ScrollWheel(dx, dy int) error {
+	return m.withPlatformSupport(func() error {
+		robotgo.Scroll(dx, dy)
+		return nil
+	})
+}
+
+func (m *mouseSimulator) Drag(from, to Point) error {
+	return m.withPlatformSupport(func() error {
+		robotgo.Move(from.X, from.Y)
+		robotgo.Toggle("left", "down")
+		robotgo.Move(to.X, to.Y)
+		robotgo.Toggle("left", "up")
+		return nil
+	})
+}
+
+func (m *mouseSimulator) ScreenSize() (Point, error) {
+	w, h := robotgo.GetScreenSize()
+	return Point{X: w, Y: h}, nil
+}
+
+func (m *mouseSimulator) Dispatch(event MouseEvent) error {
+	switch event.Kind {
+	case MouseClick:
+		return m.Click(event.Button, event.Double)
+	case MouseScroll:
+		return m.ScrollWheel(event.DX, event.DY)
+	case MouseDrag:
+		return m.Drag(event.From, event.To)
+	default:
+		return m.Move(event.X, event.Y, event.Absolute)
+	}
+}
+
+func (m *mouseSimulator) withPlatformSupport(fn func() error) error {
+	return withPlatformSupport(fn)
+}
+
+func mapButton(button string) string {
+	switch button {
+	case "right", "middle":
+		return button
+	default:
+		return "left"
+	}
+}
@@ -0,0 +1,31 @@
+package input
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPointJSONTagsAreLowercase(t *testing.T) {
+	data, err := json.Marshal(Point{X: 3, Y: 4})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := string(data), `{"x":3,"y":4}`; got != want {
+		t.Errorf("Marshal(Point) = %s, want %s", got, want)
+	}
+}
+
+func TestMapButton(t *testing.T) {
+	cases := map[string]string{
+		"right":  "right",
+		"middle": "middle",
+		"left":   "left",
+		"":       "left",
+		"bogus":  "left",
+	}
+	for in, want := range cases {
+		if got := mapButton(in); got != want {
+			t.Errorf("mapButton(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,54 +2,171 @@ package input
 
 import (
 	"fmt"
+	"log/slog"
 	"runtime"
 	"strings"
 
 	"github.com/go-vgo/robotgo"
+
+	"keyboard/observability"
 )
 
+// EventKind selects how a dispatched Event is applied to the host.
+type EventKind string
+
+const (
+	EventKeyTap  EventKind = "keytap"
+	EventKeyDown EventKind = "keydown"
+	EventKeyUp   EventKind = "keyup"
+	EventType    EventKind = "type"
+)
+
+// Event is a single input action as received from a client, independent
+// of the wire format it arrived in.
+type Event struct {
+	Kind EventKind
+	Key  string
+	Mods []string
+	Text string
+}
+
+// KeySimulator drives the host's keyboard. Implementations translate a
+// logical key name (or, for Dispatch, a full Event) into OS-level input.
 type KeySimulator interface {
 	PressKey(key string) error
+	PressKeyCombo(key string, mods []string) error
+	TypeString(text string) error
+	HoldKey(key string) error
+	ReleaseKey(key string) error
+	Dispatch(event Event) error
+	LoadKeymap(path string) error
+	SetLogger(logger *slog.Logger)
+	SetMetrics(metrics *observability.Metrics)
 }
 
-type keySimulator struct{}
+type keySimulator struct {
+	keymap  Keymap
+	logger  *slog.Logger
+	metrics *observability.Metrics
+}
 
 func NewKeySimulator() KeySimulator {
-	return &keySimulator{}
+	return &keySimulator{keymap: defaultKeymap()}
+}
+
+// SetLogger wires a logger for simulator failures. Without one, failures
+// are reported only through the returned error.
+func (k *keySimulator) SetLogger(logger *slog.Logger) {
+	k.logger = logger
+}
+
+// SetMetrics wires a Metrics to count simulator failures by platform.
+// Without one, failures aren't counted.
+func (k *keySimulator) SetMetrics(metrics *observability.Metrics) {
+	k.metrics = metrics
 }
 
 func (k *keySimulator) PressKey(key string) error {
-	switch runtime.GOOS {
-	case "linux":
-		return k.pressKeyLinux(key)
-	case "windows":
-		return k.pressKeyWindows(key)
+	return k.withPlatformSupport(func() error {
+		robotgo.KeyTap(k.mapKey(key))
+		return nil
+	})
+}
+
+func (k *keySimulator) PressKeyCombo(key string, mods []string) error {
+	return k.withPlatformSupport(func() error {
+		args := make([]interface{}, 0, len(mods))
+		for _, mod := range mods {
+			args = append(args, mapModifier(mod))
+		}
+		robotgo.KeyTap(k.mapKey(key), args...)
+		return nil
+	})
+}
+
+func (k *keySimulator) TypeString(text string) error {
+	return k.withPlatformSupport(func() error {
+		robotgo.TypeStr(text)
+		return nil
+	})
+}
+
+func (k *keySimulator) HoldKey(key string) error {
+	return k.withPlatformSupport(func() error {
+		return robotgo.KeyDown(k.mapKey(key))
+	})
+}
+
+func (k *keySimulator) ReleaseKey(key string) error {
+	return k.withPlatformSupport(func() error {
+		return robotgo.KeyUp(k.mapKey(key))
+	})
+}
+
+func (k *keySimulator) Dispatch(event Event) error {
+	switch event.Kind {
+	case EventKeyDown:
+		return k.HoldKey(event.Key)
+	case EventKeyUp:
+		return k.ReleaseKey(event.Key)
+	case EventType:
+		return k.TypeString(event.Text)
 	default:
-		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+		if len(event.Mods) > 0 {
+			return k.PressKeyCombo(event.Key, event.Mods)
+		}
+		return k.PressKey(event.Key)
 	}
 }
 
-func (k *keySimulator) pressKeyLinux(key string) error {
-	return k.pressKeyWithRobotGo(key)
+// LoadKeymap merges the remapping defined in the JSON file at path into
+// the simulator's keymap, letting users remap arbitrary input strings to
+// platform keycodes without recompiling.
+func (k *keySimulator) LoadKeymap(path string) error {
+	loaded, err := LoadKeymap(path)
+	if err != nil {
+		return err
+	}
+	for rawKey, keycode := range loaded {
+		k.keymap[rawKey] = keycode
+	}
+	return nil
 }
 
-func (k *keySimulator) pressKeyWindows(key string) error {
-	return k.pressKeyWithRobotGo(key)
+func (k *keySimulator) mapKey(key string) string {
+	if mapped, ok := k.keymap[strings.ToLower(key)]; ok {
+		return mapped
+	}
+	return key
 }
 
-func (k *keySimulator) pressKeyWithRobotGo(key string) error {
-	keyMap := map[string]string{
-		"0": "kp_0", "1": "kp_1", "2": "kp_2", "3": "kp_3", "4": "kp_4",
-		"5": "kp_5", "6": "kp_6", "7": "kp_7", "8": "kp_8", "9": "kp_9",
-		"*": "kp_multiply", "+": "kp_add", "-": "kp_subtract", ".": "kp_decimal", "/": "kp_divide",
-		"enter": "kp_enter", "backspace": "backspace", "escape": "escape",
+func (k *keySimulator) withPlatformSupport(fn func() error) error {
+	err := withPlatformSupport(fn)
+	if err != nil {
+		if k.logger != nil {
+			k.logger.Error("key simulator dispatch failed", "platform", runtime.GOOS, "error", err)
+		}
+		k.metrics.SimulatorError(runtime.GOOS)
 	}
+	return err
+}
 
-	mappedKey, exists := keyMap[strings.ToLower(key)]
-	if !exists {
-		mappedKey = key
+// withPlatformSupport runs fn on platforms robotgo supports, and fails
+// fast everywhere else. Shared by keySimulator and mouseSimulator.
+func withPlatformSupport(fn func() error) error {
+	switch runtime.GOOS {
+	case "linux", "windows", "darwin":
+		return fn()
+	default:
+		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
+}
 
-	robotgo.KeyTap(mappedKey)
-	return nil
-}
\ No newline at end of file
+func mapModifier(mod string) string {
+	switch strings.ToLower(mod) {
+	case "cmd", "command":
+		return "cmd"
+	default:
+		return strings.ToLower(mod)
+	}
+}
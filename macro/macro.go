@@ -0,0 +1,117 @@
+// Package macro persists named sequences of input events and replays them
+// through a KeySimulator on demand.
+package macro
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"keyboard/input"
+)
+
+// Event mirrors input.Event in a JSON-serializable form.
+type Event struct {
+	Kind string   `json:"kind"`
+	Key  string   `json:"key,omitempty"`
+	Mods []string `json:"mods,omitempty"`
+	Text string   `json:"text,omitempty"`
+}
+
+// Macro is a named, ordered sequence of events.
+type Macro struct {
+	Name   string  `json:"name"`
+	Events []Event `json:"events"`
+}
+
+// Registry holds every registered macro and persists them to a JSON file
+// next to the rest of the server's configuration.
+type Registry struct {
+	path string
+
+	mutex  sync.Mutex
+	macros map[string]Macro
+}
+
+// NewRegistry creates a Registry backed by the JSON file at path. Call
+// Load to read any macros already saved there.
+func NewRegistry(path string) *Registry {
+	return &Registry{
+		path:   path,
+		macros: make(map[string]Macro),
+	}
+}
+
+// Load reads macros from the registry's file. A missing file is not an
+// error; it just means no macros have been registered yet.
+func (r *Registry) Load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var macros []Macro
+	if err := json.Unmarshal(data, &macros); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, m := range macros {
+		r.macros[m.Name] = m
+	}
+	return nil
+}
+
+func (r *Registry) save() error {
+	macros := make([]Macro, 0, len(r.macros))
+	for _, m := range r.macros {
+		macros = append(macros, m)
+	}
+
+	data, err := json.MarshalIndent(macros, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0o644)
+}
+
+// Register saves a macro, overwriting any existing macro with the same
+// name, and persists the registry to disk.
+func (r *Registry) Register(m Macro) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.macros[m.Name] = m
+	return r.save()
+}
+
+// Get returns the macro registered under name, if any.
+func (r *Registry) Get(name string) (Macro, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m, ok := r.macros[name]
+	return m, ok
+}
+
+// Trigger replays the named macro's events through simulator, in order,
+// stopping at the first error.
+func (r *Registry) Trigger(name string, simulator input.KeySimulator) error {
+	m, ok := r.Get(name)
+	if !ok {
+		return fmt.Errorf("macro %q is not registered", name)
+	}
+
+	for _, e := range m.Events {
+		event := input.Event{Kind: input.EventKind(e.Kind), Key: e.Key, Mods: e.Mods, Text: e.Text}
+		if err := simulator.Dispatch(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package macro
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRegisterPersistsAndGet(t *testing.T) {
+	r := NewRegistry(filepath.Join(t.TempDir(), "macros.json"))
+
+	m := Macro{Name: "greet", Events: []Event{{Kind: "type", Text: "hi"}}}
+	if err := r.Register(m); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	got, ok := r.Get("greet")
+	if !ok {
+		t.Fatal("Get(greet) not found after Register")
+	}
+	if len(got.Events) != 1 || got.Events[0].Text != "hi" {
+		t.Errorf("Get(greet) = %+v, want events [{type hi}]", got)
+	}
+}
+
+func TestLoadReadsPersistedMacros(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "macros.json")
+
+	first := NewRegistry(path)
+	if err := first.Register(Macro{Name: "greet", Events: []Event{{Kind: "keytap", Key: "enter"}}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	second := NewRegistry(path)
+	if err := second.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := second.Get("greet"); !ok {
+		t.Error("Get(greet) not found after Load from a fresh Registry")
+	}
+}
+
+func TestTriggerUnknownMacro(t *testing.T) {
+	r := NewRegistry(filepath.Join(t.TempDir(), "macros.json"))
+	if err := r.Trigger("missing", nil); err == nil {
+		t.Error("Trigger(missing) should error for an unregistered macro")
+	}
+}
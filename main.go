@@ -5,14 +5,18 @@ import (
 	"embed"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"keyboard/auth"
 	"keyboard/config"
 	"keyboard/input"
+	"keyboard/macro"
+	"keyboard/observability"
 	"keyboard/server"
 )
 
@@ -27,12 +31,52 @@ func main() {
 		log.Fatalf("Failed to create static filesystem: %v", err)
 	}
 
+	roomConfig := server.DefaultRoomConfig()
+	roomConfig.EventLogSize = cfg.EventLogSize
+	roomConfig.ModeratorToken = cfg.ModeratorToken
+	roomConfig.MaxViewers = cfg.MaxViewers
+	roomConfig.ControllerPolicy = server.ControllerPolicy(cfg.ControllerPolicy)
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	metrics := observability.NewMetrics()
+
 	httpServer := server.NewHTTPServer(cfg, staticFS)
-	wsServer := server.NewWebSocketServer()
+	wsServer := server.NewWebSocketServer(roomConfig)
+	wsServer.SetResumeTTL(cfg.ResumeTTL)
+	wsServer.SetLogger(logger)
+	wsServer.SetMetrics(metrics)
 
 	keySimulator := input.NewKeySimulator()
+	keySimulator.SetLogger(logger)
+	keySimulator.SetMetrics(metrics)
+	if cfg.KeymapFile != "" {
+		if err := keySimulator.LoadKeymap(cfg.KeymapFile); err != nil {
+			log.Printf("Failed to load keymap %s: %v", cfg.KeymapFile, err)
+		}
+	}
 	wsServer.SetInputSimulator(keySimulator)
 
+	if cfg.MouseEnabled {
+		wsServer.SetMouseSimulator(input.NewMouseSimulator())
+	}
+
+	macroRegistry := macro.NewRegistry(cfg.MacroFile)
+	if err := macroRegistry.Load(); err != nil {
+		log.Printf("Failed to load macros from %s: %v", cfg.MacroFile, err)
+	}
+	wsServer.SetMacroRegistry(macroRegistry)
+
+	authService := auth.NewService(auth.Config{
+		Mode:     auth.Mode(cfg.AuthMode),
+		Secret:   cfg.AuthSecret,
+		TTL:      cfg.SessionTTL,
+		Username: cfg.AuthUsername,
+		Password: cfg.AuthPassword,
+	})
+	httpServer.SetAuthenticator(authService)
+	wsServer.SetAuthenticator(authService)
+	wsServer.SetRateLimiter(auth.NewRateLimiter(cfg.RateLimitPerSecond, cfg.RateLimitBurst))
+
 	go func() {
 		log.Printf("Starting server on :%s", cfg.Port)
 		if err := httpServer.Start(); err != nil && err != http.ErrServerClosed {
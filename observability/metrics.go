@@ -0,0 +1,119 @@
+// Package observability holds the server's Prometheus metrics and
+// OpenTelemetry tracing helpers, kept separate from server and input so
+// neither has to import the other to report on it.
+package observability
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the collectors exposed on /metrics. A nil *Metrics is
+// safe to call every method on (they become no-ops), so callers that
+// don't wire one in don't need a nil check at every call site.
+type Metrics struct {
+	connectionsAccepted prometheus.Counter
+	connectionsRejected *prometheus.CounterVec
+	keyEvents           *prometheus.CounterVec
+	keyPressLatency     prometheus.Histogram
+	simulatorErrors     *prometheus.CounterVec
+	pingRTT             prometheus.Histogram
+}
+
+// NewMetrics creates the server's collectors and registers them against
+// the default Prometheus registry.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		connectionsAccepted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "keyboard_connections_accepted_total",
+			Help: "WebSocket connections successfully upgraded and joined to a room.",
+		}),
+		connectionsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "keyboard_connections_rejected_total",
+			Help: "WebSocket connections rejected before joining a room, by reason.",
+		}, []string{"reason"}),
+		keyEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "keyboard_key_events_total",
+			Help: "Key events received from room members, by key name, whether or not the sender holds control.",
+		}, []string{"key"}),
+		keyPressLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "keyboard_key_press_latency_seconds",
+			Help:    "Time from reading a key message off the socket to the simulator acting on it.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		simulatorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "keyboard_simulator_errors_total",
+			Help: "Input simulator errors, by host platform (GOOS).",
+		}, []string{"platform"}),
+		pingRTT: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "keyboard_ping_rtt_seconds",
+			Help:    "Round-trip time between a keepalive ping and its pong, per connection.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+
+	prometheus.MustRegister(
+		m.connectionsAccepted,
+		m.connectionsRejected,
+		m.keyEvents,
+		m.keyPressLatency,
+		m.simulatorErrors,
+		m.pingRTT,
+	)
+	return m
+}
+
+func (m *Metrics) ConnectionAccepted() {
+	if m == nil {
+		return
+	}
+	m.connectionsAccepted.Inc()
+}
+
+// ConnectionRejected records a connection that failed before joining a
+// room, e.g. "auth", "upgrade", or "room_full".
+func (m *Metrics) ConnectionRejected(reason string) {
+	if m == nil {
+		return
+	}
+	m.connectionsRejected.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) KeyEvent(key string) {
+	if m == nil {
+		return
+	}
+	m.keyEvents.WithLabelValues(key).Inc()
+}
+
+func (m *Metrics) ObserveKeyPressLatency(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.keyPressLatency.Observe(d.Seconds())
+}
+
+// SimulatorError records a failed simulator call, tagged with the
+// platform (runtime.GOOS) it failed on.
+func (m *Metrics) SimulatorError(platform string) {
+	if m == nil {
+		return
+	}
+	m.simulatorErrors.WithLabelValues(platform).Inc()
+}
+
+func (m *Metrics) ObservePingRTT(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.pingRTT.Observe(d.Seconds())
+}
+
+// Handler serves the Prometheus exposition format for the collectors
+// registered by NewMetrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,27 @@
+package observability
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer resolves to whatever TracerProvider the process installed via
+// otel.SetTracerProvider; with none installed it's a no-op, so keystroke
+// tracing costs nothing until an operator wires up an SDK.
+var tracer = otel.Tracer("keyboard")
+
+// StartKeystrokeSpan opens a span for one keystroke, tagged with the
+// room and session it belongs to. The server opens it once the message
+// has been parsed and the key event is handed to the room, and it ends
+// when the simulator call returns. Neither message parsing nor the
+// one-time connection upgrade falls inside the span.
+// Callers must End the returned span.
+func StartKeystrokeSpan(ctx context.Context, roomID, sessionID string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "keyboard.keystroke", trace.WithAttributes(
+		attribute.String("room.id", roomID),
+		attribute.String("session.id", sessionID),
+	))
+}
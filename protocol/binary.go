@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Subprotocol is the Sec-WebSocket-Protocol token clients offer to speak
+// the binary framing in this file.
+const Subprotocol = "keyboard.v2.bin"
+
+// BinaryCodec packs frames back to back as
+// [opcode:1][mods:1][keyID:2][payloadLen:2][payload], so a client can
+// coalesce several events accumulated within a short window into one
+// WebSocket message instead of sending one message per keystroke.
+type BinaryCodec struct{}
+
+const frameHeaderSize = 6
+
+func (BinaryCodec) Encode(frames []Frame) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, f := range frames {
+		payload := []byte(f.Text)
+		if len(payload) > 0xFFFF {
+			return nil, fmt.Errorf("protocol: frame payload too large (%d bytes)", len(payload))
+		}
+
+		buf.WriteByte(byte(f.Opcode))
+		buf.WriteByte(byte(f.Mods))
+		var header [4]byte
+		binary.LittleEndian.PutUint16(header[0:2], uint16(f.Key))
+		binary.LittleEndian.PutUint16(header[2:4], uint16(len(payload)))
+		buf.Write(header[:])
+		buf.Write(payload)
+	}
+	return buf.Bytes(), nil
+}
+
+func (BinaryCodec) Decode(data []byte) ([]Frame, error) {
+	var frames []Frame
+	r := bytes.NewReader(data)
+
+	for r.Len() > 0 {
+		var header [frameHeaderSize]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("protocol: truncated frame header: %w", err)
+		}
+
+		opcode := Opcode(header[0])
+		mods := ModMask(header[1])
+		key := KeyID(binary.LittleEndian.Uint16(header[2:4]))
+		payloadLen := binary.LittleEndian.Uint16(header[4:6])
+
+		payload := make([]byte, payloadLen)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, fmt.Errorf("protocol: truncated frame payload: %w", err)
+		}
+
+		frames = append(frames, Frame{Opcode: opcode, Mods: mods, Key: key, Text: string(payload)})
+	}
+	return frames, nil
+}
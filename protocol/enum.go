@@ -0,0 +1,171 @@
+package protocol
+
+import "strings"
+
+// ModMask is a bitmask of held modifier keys, carried as a single byte on
+// the wire.
+type ModMask byte
+
+const (
+	ModCtrl ModMask = 1 << iota
+	ModAlt
+	ModShift
+	ModMeta
+)
+
+var modifierBits = []struct {
+	mask ModMask
+	name string
+}{
+	{ModCtrl, "ctrl"},
+	{ModAlt, "alt"},
+	{ModShift, "shift"},
+	{ModMeta, "meta"},
+}
+
+// Names returns the modifier names set in m.
+func (m ModMask) Names() []string {
+	var names []string
+	for _, bit := range modifierBits {
+		if m&bit.mask != 0 {
+			names = append(names, bit.name)
+		}
+	}
+	return names
+}
+
+// ModMaskFromNames builds the ModMask carrying every recognized name.
+// Unrecognized names are ignored.
+func ModMaskFromNames(names []string) ModMask {
+	var mask ModMask
+	for _, name := range names {
+		for _, bit := range modifierBits {
+			if strings.EqualFold(bit.name, name) {
+				mask |= bit.mask
+			}
+		}
+	}
+	return mask
+}
+
+// KeyID is the shared numeric key enum carried in binary frames, so the
+// wire never has to spell out key names.
+type KeyID uint16
+
+const (
+	KeyNone KeyID = iota
+	Key0
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+	KeyEnter
+	KeyBackspace
+	KeyEscape
+	KeySpace
+	KeyTab
+	KeyA
+	KeyB
+	KeyC
+	KeyD
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+	KeyArrowUp
+	KeyArrowDown
+	KeyArrowLeft
+	KeyArrowRight
+)
+
+var keyNames = map[KeyID]string{
+	KeyNone:      "",
+	Key0:         "0",
+	Key1:         "1",
+	Key2:         "2",
+	Key3:         "3",
+	Key4:         "4",
+	Key5:         "5",
+	Key6:         "6",
+	Key7:         "7",
+	Key8:         "8",
+	Key9:         "9",
+	KeyEnter:     "enter",
+	KeyBackspace: "backspace",
+	KeyEscape:    "escape",
+	KeySpace:     "space",
+	KeyTab:       "tab",
+	KeyA:         "a",
+	KeyB:         "b",
+	KeyC:         "c",
+	KeyD:         "d",
+	KeyE:         "e",
+	KeyF:         "f",
+	KeyG:         "g",
+	KeyH:         "h",
+	KeyI:         "i",
+	KeyJ:         "j",
+	KeyK:         "k",
+	KeyL:         "l",
+	KeyM:         "m",
+	KeyN:         "n",
+	KeyO:         "o",
+	KeyP:         "p",
+	KeyQ:         "q",
+	KeyR:         "r",
+	KeyS:         "s",
+	KeyT:         "t",
+	KeyU:         "u",
+	KeyV:         "v",
+	KeyW:         "w",
+	KeyX:         "x",
+	KeyY:         "y",
+	KeyZ:         "z",
+	KeyArrowUp:    "arrowup",
+	KeyArrowDown:  "arrowdown",
+	KeyArrowLeft:  "arrowleft",
+	KeyArrowRight: "arrowright",
+}
+
+var keyIDsByName map[string]KeyID
+
+func init() {
+	keyIDsByName = make(map[string]KeyID, len(keyNames))
+	for id, name := range keyNames {
+		keyIDsByName[name] = id
+	}
+}
+
+// Name returns the logical key string for k, or "" for an unknown ID.
+func (k KeyID) Name() string {
+	return keyNames[k]
+}
+
+// KeyIDForName looks up the KeyID for a logical key string, matching the
+// same names the JSON protocol uses. Unknown names map to KeyNone.
+func KeyIDForName(name string) KeyID {
+	return keyIDsByName[strings.ToLower(name)]
+}
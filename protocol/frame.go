@@ -0,0 +1,67 @@
+// Package protocol defines the wire formats a client can use to drive
+// key events: the original JSON form, and a compact binary form for
+// low-latency, batched input. Both codecs operate on the same []Frame
+// model so they can be tested and dispatched identically.
+package protocol
+
+import "keyboard/input"
+
+// Opcode selects how a Frame is interpreted.
+type Opcode byte
+
+const (
+	OpKeyTap Opcode = iota
+	OpKeyDown
+	OpKeyUp
+	OpType
+	OpPing
+	OpAck
+)
+
+// Frame is a single input action in wire form: a 1-byte opcode, a 1-byte
+// modifier bitmask, a 2-byte key ID, and a variable-length payload (only
+// used by OpType, which carries UTF-8 text to type).
+type Frame struct {
+	Opcode Opcode
+	Mods   ModMask
+	Key    KeyID
+	Text   string
+}
+
+// Event converts a key/type Frame into the shared input.Event model.
+// OpPing and OpAck carry no key event and convert to the zero value.
+func (f Frame) Event() input.Event {
+	switch f.Opcode {
+	case OpKeyDown:
+		return input.Event{Kind: input.EventKeyDown, Key: f.Key.Name()}
+	case OpKeyUp:
+		return input.Event{Kind: input.EventKeyUp, Key: f.Key.Name()}
+	case OpType:
+		return input.Event{Kind: input.EventType, Text: f.Text}
+	case OpKeyTap:
+		return input.Event{Kind: input.EventKeyTap, Key: f.Key.Name(), Mods: f.Mods.Names()}
+	default:
+		return input.Event{}
+	}
+}
+
+// FrameForEvent converts an input.Event into its wire Frame.
+func FrameForEvent(event input.Event) Frame {
+	switch event.Kind {
+	case input.EventKeyDown:
+		return Frame{Opcode: OpKeyDown, Key: KeyIDForName(event.Key)}
+	case input.EventKeyUp:
+		return Frame{Opcode: OpKeyUp, Key: KeyIDForName(event.Key)}
+	case input.EventType:
+		return Frame{Opcode: OpType, Text: event.Text}
+	default:
+		return Frame{Opcode: OpKeyTap, Key: KeyIDForName(event.Key), Mods: ModMaskFromNames(event.Mods)}
+	}
+}
+
+// Codec encodes and decodes a batch of Frames to and from a single
+// WebSocket message.
+type Codec interface {
+	Encode(frames []Frame) ([]byte, error)
+	Decode(data []byte) ([]Frame, error)
+}
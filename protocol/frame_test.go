@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sampleFrames() []Frame {
+	return []Frame{
+		{Opcode: OpKeyTap, Key: KeyEnter},
+		{Opcode: OpKeyTap, Key: KeyA, Mods: ModCtrl | ModShift},
+		{Opcode: OpKeyDown, Key: KeyZ},
+		{Opcode: OpKeyUp, Key: KeyZ},
+		{Opcode: OpType, Text: "hello, world"},
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	codecs := map[string]Codec{
+		"binary": BinaryCodec{},
+		"json":   JSONCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			frames := sampleFrames()
+
+			data, err := codec.Encode(frames)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got, err := codec.Decode(data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+
+			if len(got) != len(frames) {
+				t.Fatalf("got %d frames, want %d", len(got), len(frames))
+			}
+			for i := range frames {
+				if !reflect.DeepEqual(got[i].Event(), frames[i].Event()) {
+					t.Errorf("frame %d: got %+v, want %+v", i, got[i].Event(), frames[i].Event())
+				}
+			}
+		})
+	}
+}
+
+func TestKeyIDForName(t *testing.T) {
+	if got := KeyIDForName("ENTER"); got != KeyEnter {
+		t.Errorf("KeyIDForName(ENTER) = %v, want %v", got, KeyEnter)
+	}
+	if got := KeyIDForName("not-a-key"); got != KeyNone {
+		t.Errorf("KeyIDForName(not-a-key) = %v, want KeyNone", got)
+	}
+}
+
+func TestBinaryCodecDecodeTruncated(t *testing.T) {
+	if _, err := (BinaryCodec{}).Decode([]byte{1, 2, 3}); err == nil {
+		t.Error("expected error decoding truncated frame, got nil")
+	}
+}
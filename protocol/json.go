@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"encoding/json"
+
+	"keyboard/input"
+)
+
+// LegacySubprotocol is the Sec-WebSocket-Protocol token clients offer to
+// speak the original JSON-per-keystroke framing, kept for backward
+// compatibility with clients that predate BinaryCodec.
+const LegacySubprotocol = "keyboard.v1.json"
+
+type jsonFrame struct {
+	Kind string   `json:"kind"`
+	Key  string   `json:"key,omitempty"`
+	Mods []string `json:"mods,omitempty"`
+	Text string   `json:"text,omitempty"`
+}
+
+// JSONCodec encodes frames as a JSON array, for parity with BinaryCodec
+// when batching, and as the wire format negotiated under
+// LegacySubprotocol.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(frames []Frame) ([]byte, error) {
+	out := make([]jsonFrame, 0, len(frames))
+	for _, f := range frames {
+		event := f.Event()
+		out = append(out, jsonFrame{Kind: string(event.Kind), Key: event.Key, Mods: event.Mods, Text: event.Text})
+	}
+	return json.Marshal(out)
+}
+
+func (JSONCodec) Decode(data []byte) ([]Frame, error) {
+	var in []jsonFrame
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+
+	frames := make([]Frame, 0, len(in))
+	for _, jf := range in {
+		event := input.Event{Kind: input.EventKind(jf.Kind), Key: jf.Key, Mods: jf.Mods, Text: jf.Text}
+		frames = append(frames, FrameForEvent(event))
+	}
+	return frames, nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"keyboard/auth"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// handleAuth validates the login credentials on the request (HTTP Basic,
+// or a shared secret presented as a token) and mints a short-lived
+// session token a client can then present to /ws/{room}.
+func (s *WebSocketServer) handleAuth(w http.ResponseWriter, r *http.Request) {
+	subject, err := s.auth.Login(r)
+	if err != nil {
+		http.Error(w, err.Error(), auth.StatusCode(err))
+		return
+	}
+
+	token, err := s.auth.MintToken(subject)
+	if err != nil {
+		http.Error(w, "failed to mint token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"expires_in": int(s.auth.TTL().Seconds()),
+	})
+}
+
+// handleAuthQR mints a session token and renders it, together with the
+// room's WebSocket URL, as a QR code a phone can scan to connect without
+// retyping credentials.
+func (s *WebSocketServer) handleAuthQR(w http.ResponseWriter, r *http.Request) {
+	roomName := r.URL.Query().Get("room")
+	if roomName == "" {
+		roomName = "default"
+	}
+
+	subject, err := s.auth.Login(r)
+	if err != nil {
+		http.Error(w, err.Error(), auth.StatusCode(err))
+		return
+	}
+
+	token, err := s.auth.MintToken(subject)
+	if err != nil {
+		http.Error(w, "failed to mint token", http.StatusInternalServerError)
+		return
+	}
+
+	scheme := "ws"
+	if r.TLS != nil {
+		scheme = "wss"
+	}
+	pairingURL := fmt.Sprintf("%s://%s/ws/%s?token=%s", scheme, r.Host, roomName, token)
+
+	png, err := qrcode.Encode(pairingURL, qrcode.Medium, 256)
+	if err != nil {
+		http.Error(w, "failed to render QR code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
@@ -0,0 +1,55 @@
+package server
+
+import "testing"
+
+func TestEventsSinceReturnsOnlyNewerEvents(t *testing.T) {
+	m := NewRoomManager(DefaultRoomConfig())
+
+	m.LogAndDeliver("room", "", []byte("one"))
+	m.LogAndDeliver("room", "", []byte("two"))
+	m.LogAndDeliver("room", "", []byte("three"))
+
+	all := m.EventsSince("room", 0, "")
+	if len(all) != 3 {
+		t.Fatalf("EventsSince(0) = %d events, want 3", len(all))
+	}
+
+	newer := m.EventsSince("room", all[0].id, "")
+	if len(newer) != 2 || string(newer[0].data) != "two" || string(newer[1].data) != "three" {
+		t.Errorf("EventsSince(%d) = %+v, want [two three]", all[0].id, newer)
+	}
+}
+
+func TestEventsSinceFiltersByTarget(t *testing.T) {
+	m := NewRoomManager(DefaultRoomConfig())
+
+	m.LogAndDeliver("room", "", []byte("broadcast"))
+	m.LogAndDeliver("room", "m1", []byte("for-m1"))
+	m.LogAndDeliver("room", "m2", []byte("for-m2"))
+
+	got := m.EventsSince("room", 0, "m1")
+	if len(got) != 2 {
+		t.Fatalf("EventsSince(0, m1) = %d events, want 2 (broadcast + for-m1)", len(got))
+	}
+	if string(got[0].data) != "broadcast" || string(got[1].data) != "for-m1" {
+		t.Errorf("EventsSince(0, m1) = %+v, want [broadcast for-m1]", got)
+	}
+}
+
+func TestEventLogEvictsOldestPastCapacity(t *testing.T) {
+	cfg := DefaultRoomConfig()
+	cfg.EventLogSize = 2
+	m := NewRoomManager(cfg)
+
+	m.LogAndDeliver("room", "", []byte("one"))
+	m.LogAndDeliver("room", "", []byte("two"))
+	m.LogAndDeliver("room", "", []byte("three"))
+
+	got := m.EventsSince("room", 0, "")
+	if len(got) != 2 {
+		t.Fatalf("EventsSince(0) = %d events, want 2 after eviction", len(got))
+	}
+	if string(got[0].data) != "two" || string(got[1].data) != "three" {
+		t.Errorf("EventsSince(0) = %+v, want [two three]", got)
+	}
+}
@@ -6,15 +6,17 @@ import (
 	"net/http"
 	"time"
 
+	"keyboard/auth"
 	"keyboard/config"
 
 	"github.com/gorilla/mux"
 )
 
 type HTTPServer struct {
-	server  *http.Server
-	router  *mux.Router
+	server   *http.Server
+	router   *mux.Router
 	staticFS fs.FS
+	auth     *auth.Service
 }
 
 func NewHTTPServer(cfg *config.Config, staticFS fs.FS) *HTTPServer {
@@ -37,6 +39,13 @@ func (s *HTTPServer) Router() *mux.Router {
 	return s.router
 }
 
+// SetAuthenticator wires the auth.Service the static file handler uses
+// to challenge first-time visitors and set a signed session cookie.
+// Without one, static files are served unauthenticated.
+func (s *HTTPServer) SetAuthenticator(service *auth.Service) {
+	s.auth = service
+}
+
 func (s *HTTPServer) Start() error {
 	s.setupStaticFiles()
 	return s.server.ListenAndServe()
@@ -47,5 +56,41 @@ func (s *HTTPServer) Shutdown(ctx context.Context) error {
 }
 
 func (s *HTTPServer) setupStaticFiles() {
-	s.router.PathPrefix("/").Handler(http.FileServer(http.FS(s.staticFS)))
+	s.router.PathPrefix("/").Handler(s.requireSession(http.FileServer(http.FS(s.staticFS))))
+}
+
+// requireSession wraps next so that, when an authenticator is configured,
+// a visitor either already holds a valid "session" cookie or logs in
+// (HTTP Basic, or a shared secret presented as a token) and is issued
+// one.
+func (s *HTTPServer) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil || !s.auth.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if cookie, err := r.Cookie("session"); err == nil {
+			if _, ok := s.auth.VerifyCookie(cookie.Value); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		subject, err := s.auth.Login(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="keyboard"`)
+			http.Error(w, err.Error(), auth.StatusCode(err))
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     "session",
+			Value:    s.auth.SignCookie(subject),
+			Path:     "/",
+			HttpOnly: true,
+			MaxAge:   int(s.auth.TTL().Seconds()),
+		})
+		next.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,547 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"keyboard/input"
+	"keyboard/observability"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Role describes what a room member is allowed to do.
+type Role string
+
+const (
+	RoleObserver   Role = "observer"
+	RoleController Role = "controller"
+	RoleModerator  Role = "moderator"
+)
+
+// ControllerPolicy decides how control of a room is handed out as members
+// join and leave.
+type ControllerPolicy string
+
+const (
+	// PolicyFirstComeFirstServed makes the first member to join a room its
+	// controller; later joiners become observers.
+	PolicyFirstComeFirstServed ControllerPolicy = "first-come"
+	// PolicyModeratorAssigned leaves every joiner as an observer until a
+	// moderator explicitly grants control.
+	PolicyModeratorAssigned ControllerPolicy = "moderator-assigned"
+)
+
+// RoomConfig controls how rooms are sized and how control is awarded.
+type RoomConfig struct {
+	MaxViewers       int
+	ControllerPolicy ControllerPolicy
+	ModeratorToken   string
+	// EventLogSize is how many outbound messages each room retains in
+	// its bounded event log, for replay to a reconnecting client.
+	EventLogSize int
+}
+
+// DefaultRoomConfig returns the configuration used when none is supplied.
+func DefaultRoomConfig() RoomConfig {
+	return RoomConfig{
+		MaxViewers:       8,
+		ControllerPolicy: PolicyFirstComeFirstServed,
+		EventLogSize:     64,
+	}
+}
+
+// Member is a single participant in a room.
+type Member struct {
+	ID   string
+	Name string
+	Role Role
+	// Subject identifies the authenticated principal behind this member,
+	// as returned by auth.Service.Authenticate. Empty when auth is
+	// disabled.
+	Subject string
+
+	conn *Connection
+}
+
+// MemberSummary is the JSON-safe view of a Member exposed over HTTP and in
+// broadcast state messages.
+type MemberSummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+// Room groups the members currently connected under the same room name.
+type Room struct {
+	Name string
+
+	mutex      sync.Mutex
+	members    map[string]*Member
+	controller *Member
+	lastKey    string
+
+	// eventLog is a bounded, append-only log of outbound messages, so a
+	// client that reconnects within its session TTL can replay what it
+	// missed instead of losing it. Entries are tagged with the member
+	// they target, or "" for a broadcast to every member.
+	eventLog    []roomEvent
+	eventCap    int
+	nextEventID uint64
+}
+
+// roomEvent is a single outbound message recorded in a room's event log.
+type roomEvent struct {
+	id     uint64
+	target string
+	data   []byte
+}
+
+func newRoom(name string, eventCap int) *Room {
+	if eventCap <= 0 {
+		eventCap = 1
+	}
+	return &Room{
+		Name:     name,
+		members:  make(map[string]*Member),
+		eventCap: eventCap,
+	}
+}
+
+// recordEvent appends data to the room's event log under id, evicting the
+// oldest entry once the log is at capacity. Callers must hold r.mutex.
+func (r *Room) recordEvent(id uint64, target string, data []byte) {
+	if len(r.eventLog) >= r.eventCap {
+		r.eventLog = r.eventLog[1:]
+	}
+	r.eventLog = append(r.eventLog, roomEvent{id: id, target: target, data: data})
+}
+
+// eventsSince returns every logged event after lastID that is either a
+// broadcast or addressed to member, oldest first.
+func (r *Room) eventsSince(lastID uint64, member string) []roomEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var out []roomEvent
+	for _, evt := range r.eventLog {
+		if evt.id <= lastID {
+			continue
+		}
+		if evt.target != "" && evt.target != member {
+			continue
+		}
+		out = append(out, evt)
+	}
+	return out
+}
+
+// RoomSummary is the JSON-safe view of a Room exposed over HTTP.
+type RoomSummary struct {
+	Name       string          `json:"name"`
+	Members    []MemberSummary `json:"members"`
+	Controller string          `json:"controller,omitempty"`
+	LastKey    string          `json:"lastKey,omitempty"`
+}
+
+func (r *Room) summary() RoomSummary {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	summary := RoomSummary{Name: r.Name, LastKey: r.lastKey}
+	for _, m := range r.members {
+		summary.Members = append(summary.Members, MemberSummary{ID: m.ID, Name: m.Name, Role: m.Role})
+	}
+	if r.controller != nil {
+		summary.Controller = r.controller.ID
+	}
+	return summary
+}
+
+// stateMessage is broadcast to every member whenever room membership or
+// control changes.
+type stateMessage struct {
+	Type       string          `json:"type"`
+	Controller string          `json:"controller,omitempty"`
+	Members    []MemberSummary `json:"members"`
+	LastKey    string          `json:"lastKey,omitempty"`
+}
+
+func (r *Room) broadcastState() {
+	r.mutex.Lock()
+	msg := stateMessage{Type: "state", LastKey: r.lastKey}
+	for _, m := range r.members {
+		msg.Members = append(msg.Members, MemberSummary{ID: m.ID, Name: m.Name, Role: m.Role})
+	}
+	if r.controller != nil {
+		msg.Controller = r.controller.ID
+	}
+	members := make([]*Member, 0, len(r.members))
+	for _, m := range r.members {
+		members = append(members, m)
+	}
+	r.mutex.Unlock()
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		slog.Default().Error("Failed to marshal room state", "error", err)
+		return
+	}
+
+	r.mutex.Lock()
+	r.nextEventID++
+	r.recordEvent(r.nextEventID, "", payload)
+	r.mutex.Unlock()
+
+	for _, m := range members {
+		m.conn.trySendText(payload)
+	}
+}
+
+// ErrRoomFull is returned by RoomManager.Join when a room has reached its
+// configured viewer capacity.
+var ErrRoomFull = fmt.Errorf("room is full")
+
+// RoomManager owns every Room and serializes join/leave/control/key
+// operations through a single goroutine so room state never needs its own
+// locking beyond bookkeeping.
+type RoomManager struct {
+	cfg     RoomConfig
+	logger  *slog.Logger
+	metrics *observability.Metrics
+
+	mutex sync.Mutex
+	rooms map[string]*Room
+
+	join    chan *joinRequest
+	leave   chan *leaveRequest
+	control chan *controlRequest
+	action  chan *actionEvent
+	mouse   chan *mouseActionEvent
+}
+
+type joinRequest struct {
+	room   string
+	member *Member
+	result chan error
+}
+
+type leaveRequest struct {
+	room     string
+	memberID string
+}
+
+type controlRequest struct {
+	room   string
+	actor  *Member
+	action string
+	target string
+}
+
+type actionEvent struct {
+	room  string
+	actor *Member
+	event input.Event
+
+	// receivedAt is when the key message was read off the socket, used
+	// to measure key-press latency once the simulator has acted on it.
+	receivedAt time.Time
+	// span covers the keystroke from receipt through the simulator call
+	// returning; handleAction ends it.
+	span trace.Span
+}
+
+type mouseActionEvent struct {
+	room  string
+	actor *Member
+	event input.MouseEvent
+}
+
+// NewRoomManager creates a RoomManager with the given configuration. Call
+// Run in its own goroutine to start processing room events.
+func NewRoomManager(cfg RoomConfig) *RoomManager {
+	return &RoomManager{
+		cfg:     cfg,
+		rooms:   make(map[string]*Room),
+		join:    make(chan *joinRequest),
+		leave:   make(chan *leaveRequest),
+		control: make(chan *controlRequest),
+		action:  make(chan *actionEvent),
+		mouse:   make(chan *mouseActionEvent),
+	}
+}
+
+// Run processes room membership, control transfer, and input-action
+// events until the manager is stopped. It is the only goroutine that
+// mutates room membership or controller assignment. mouseSimulator may
+// be nil, in which case mouse events are dropped.
+func (m *RoomManager) Run(simulator input.KeySimulator, mouseSimulator input.MouseSimulator) {
+	for {
+		select {
+		case req := <-m.join:
+			req.result <- m.handleJoin(req.room, req.member)
+
+		case req := <-m.leave:
+			m.handleLeave(req.room, req.memberID)
+
+		case req := <-m.control:
+			m.handleControl(req)
+
+		case evt := <-m.action:
+			m.handleAction(evt, simulator)
+
+		case evt := <-m.mouse:
+			m.handleMouseAction(evt, mouseSimulator)
+		}
+	}
+}
+
+// SetLogger wires a structured logger. Without one, slog.Default() is
+// used.
+func (m *RoomManager) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
+// SetMetrics wires the Prometheus collectors recorded against key and
+// mouse dispatch. Without one, nothing is recorded.
+func (m *RoomManager) SetMetrics(metrics *observability.Metrics) {
+	m.metrics = metrics
+}
+
+func (m *RoomManager) log() *slog.Logger {
+	if m.logger != nil {
+		return m.logger
+	}
+	return slog.Default()
+}
+
+func (m *RoomManager) roomFor(name string) *Room {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	room, ok := m.rooms[name]
+	if !ok {
+		room = newRoom(name, m.cfg.EventLogSize)
+		m.rooms[name] = room
+	}
+	return room
+}
+
+func (m *RoomManager) handleJoin(roomName string, member *Member) error {
+	room := m.roomFor(roomName)
+
+	room.mutex.Lock()
+	if m.cfg.MaxViewers > 0 && len(room.members) >= m.cfg.MaxViewers {
+		room.mutex.Unlock()
+		return ErrRoomFull
+	}
+
+	if member.Role != RoleModerator {
+		switch m.cfg.ControllerPolicy {
+		case PolicyModeratorAssigned:
+			member.Role = RoleObserver
+		default:
+			if room.controller == nil {
+				member.Role = RoleController
+				room.controller = member
+			} else {
+				member.Role = RoleObserver
+			}
+		}
+	}
+	room.members[member.ID] = member
+	room.mutex.Unlock()
+
+	m.log().Info("member joined room", "member", member.ID, "room", roomName, "role", member.Role)
+	room.broadcastState()
+	return nil
+}
+
+func (m *RoomManager) handleLeave(roomName, memberID string) {
+	room := m.roomFor(roomName)
+
+	room.mutex.Lock()
+	member, ok := room.members[memberID]
+	if !ok {
+		room.mutex.Unlock()
+		return
+	}
+	delete(room.members, memberID)
+	if room.controller == member {
+		room.controller = nil
+		if m.cfg.ControllerPolicy == PolicyFirstComeFirstServed {
+			for _, next := range room.members {
+				next.Role = RoleController
+				room.controller = next
+				break
+			}
+		}
+	}
+	room.mutex.Unlock()
+
+	m.log().Info("member left room", "member", memberID, "room", roomName)
+	room.broadcastState()
+}
+
+func (m *RoomManager) handleControl(req *controlRequest) {
+	if req.actor.Role != RoleModerator {
+		return
+	}
+
+	room := m.roomFor(req.room)
+	room.mutex.Lock()
+	target, ok := room.members[req.target]
+	if !ok {
+		room.mutex.Unlock()
+		return
+	}
+
+	switch req.action {
+	case "grant":
+		if room.controller != nil && room.controller != target {
+			room.controller.Role = RoleObserver
+		}
+		target.Role = RoleController
+		room.controller = target
+	case "revoke":
+		if room.controller == target {
+			target.Role = RoleObserver
+			room.controller = nil
+		}
+	}
+	room.mutex.Unlock()
+
+	m.log().Info("moderator changed control", "moderator", req.actor.ID, "action", req.action, "room", req.room, "target", req.target)
+	room.broadcastState()
+}
+
+func (m *RoomManager) handleAction(evt *actionEvent, simulator input.KeySimulator) {
+	if evt.span != nil {
+		defer evt.span.End()
+	}
+
+	room := m.roomFor(evt.room)
+
+	room.mutex.Lock()
+	isController := room.controller == evt.actor
+	if isController && evt.event.Key != "" {
+		room.lastKey = evt.event.Key
+	}
+	room.mutex.Unlock()
+
+	if !isController {
+		return
+	}
+
+	if simulator != nil {
+		if err := simulator.Dispatch(evt.event); err != nil {
+			// The simulator itself already counts this in
+			// keyboard_simulator_errors_total (it's the one that knows
+			// the failure actually reached withPlatformSupport); don't
+			// double-count it here too.
+			m.log().Error("Input dispatch error", "error", err)
+			if evt.span != nil {
+				evt.span.RecordError(err)
+			}
+		} else {
+			m.metrics.ObserveKeyPressLatency(time.Since(evt.receivedAt))
+		}
+	}
+	room.broadcastState()
+}
+
+// handleMouseAction applies a pointer/scroll event on behalf of the
+// room's controller, the same permission required for key events: an
+// observer cannot move the pointer.
+func (m *RoomManager) handleMouseAction(evt *mouseActionEvent, simulator input.MouseSimulator) {
+	room := m.roomFor(evt.room)
+
+	room.mutex.Lock()
+	isController := room.controller == evt.actor
+	room.mutex.Unlock()
+
+	if !isController || simulator == nil {
+		return
+	}
+
+	if err := simulator.Dispatch(evt.event); err != nil {
+		m.log().Error("Mouse dispatch error", "error", err)
+		m.metrics.SimulatorError(runtime.GOOS)
+	}
+}
+
+// LogAndDeliver appends data to roomName's event log — addressed to
+// target, or every member if target is "" — then immediately delivers it
+// to whichever of those members is currently connected. Used for
+// per-member replies (macro acks, errors) that should survive the same
+// brief reconnects as the room's broadcast state.
+func (m *RoomManager) LogAndDeliver(roomName, target string, data []byte) {
+	room := m.roomFor(roomName)
+
+	room.mutex.Lock()
+	room.nextEventID++
+	room.recordEvent(room.nextEventID, target, data)
+	var recipients []*Member
+	if target == "" {
+		for _, mem := range room.members {
+			recipients = append(recipients, mem)
+		}
+	} else if mem, ok := room.members[target]; ok {
+		recipients = append(recipients, mem)
+	}
+	room.mutex.Unlock()
+
+	for _, mem := range recipients {
+		mem.conn.trySendText(data)
+	}
+}
+
+// EventsSince returns every event in roomName's log that postdates lastID
+// and targets member (or is a broadcast), for replay to a reconnecting
+// client.
+func (m *RoomManager) EventsSince(roomName string, lastID uint64, member string) []roomEvent {
+	return m.roomFor(roomName).eventsSince(lastID, member)
+}
+
+// IsController reports whether member currently holds control of the
+// named room. It is used to gate macro triggers, which bypass the
+// action channel since they replay a precomputed sequence of events.
+func (m *RoomManager) IsController(roomName string, member *Member) bool {
+	room := m.roomFor(roomName)
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+	return room.controller == member
+}
+
+// List returns a summary of every known room, for the HTTP listing
+// endpoint.
+func (m *RoomManager) List() []RoomSummary {
+	m.mutex.Lock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mutex.Unlock()
+
+	summaries := make([]RoomSummary, 0, len(rooms))
+	for _, room := range rooms {
+		summaries = append(summaries, room.summary())
+	}
+	return summaries
+}
+
+// Members returns the member summary for a single room, and false if the
+// room does not exist.
+func (m *RoomManager) Members(name string) ([]MemberSummary, bool) {
+	m.mutex.Lock()
+	room, ok := m.rooms[name]
+	m.mutex.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return room.summary().Members, true
+}
@@ -0,0 +1,94 @@
+package server
+
+import "testing"
+
+func newTestMember(id string) *Member {
+	return &Member{ID: id, conn: &Connection{send: make(chan wsMessage, 8)}}
+}
+
+func TestFirstComeFirstServedControllerElection(t *testing.T) {
+	m := NewRoomManager(DefaultRoomConfig())
+
+	first := newTestMember("m1")
+	second := newTestMember("m2")
+
+	if err := m.handleJoin("room", first); err != nil {
+		t.Fatalf("handleJoin(first): %v", err)
+	}
+	if err := m.handleJoin("room", second); err != nil {
+		t.Fatalf("handleJoin(second): %v", err)
+	}
+
+	if first.Role != RoleController {
+		t.Errorf("first joiner role = %v, want controller", first.Role)
+	}
+	if second.Role != RoleObserver {
+		t.Errorf("second joiner role = %v, want observer", second.Role)
+	}
+	if !m.IsController("room", first) {
+		t.Error("IsController(first) = false, want true")
+	}
+}
+
+func TestControllerHandoverOnLeave(t *testing.T) {
+	m := NewRoomManager(DefaultRoomConfig())
+
+	first := newTestMember("m1")
+	second := newTestMember("m2")
+	m.handleJoin("room", first)
+	m.handleJoin("room", second)
+
+	m.handleLeave("room", first.ID)
+
+	if second.Role != RoleController {
+		t.Errorf("remaining member role = %v, want controller after handover", second.Role)
+	}
+	if !m.IsController("room", second) {
+		t.Error("IsController(second) = false, want true after handover")
+	}
+}
+
+func TestModeratorAssignedPolicyRequiresExplicitGrant(t *testing.T) {
+	cfg := DefaultRoomConfig()
+	cfg.ControllerPolicy = PolicyModeratorAssigned
+	m := NewRoomManager(cfg)
+
+	moderator := newTestMember("mod")
+	moderator.Role = RoleModerator
+	observer := newTestMember("obs")
+
+	m.handleJoin("room", moderator)
+	m.handleJoin("room", observer)
+
+	if observer.Role != RoleObserver {
+		t.Fatalf("joiner role = %v, want observer under moderator-assigned policy", observer.Role)
+	}
+	if m.IsController("room", observer) {
+		t.Fatal("observer should not hold control before a grant")
+	}
+
+	m.handleControl(&controlRequest{room: "room", actor: moderator, action: "grant", target: observer.ID})
+	if !m.IsController("room", observer) {
+		t.Error("IsController(observer) = false after grant, want true")
+	}
+
+	m.handleControl(&controlRequest{room: "room", actor: moderator, action: "revoke", target: observer.ID})
+	if m.IsController("room", observer) {
+		t.Error("IsController(observer) = true after revoke, want false")
+	}
+}
+
+func TestHandleControlIgnoresNonModerator(t *testing.T) {
+	m := NewRoomManager(DefaultRoomConfig())
+
+	controller := newTestMember("m1")
+	observer := newTestMember("m2")
+	m.handleJoin("room", controller)
+	m.handleJoin("room", observer)
+
+	m.handleControl(&controlRequest{room: "room", actor: observer, action: "grant", target: observer.ID})
+
+	if m.IsController("room", observer) {
+		t.Error("a non-moderator actor should not be able to grant control")
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resumeProtocolPrefix is the Sec-WebSocket-Protocol token prefix a
+// client offers to resume a prior session:
+// "keyboard.resume.<session-id>.<last-event-id>". It coexists with the
+// codec and auth tokens also carried in that header, since the header is
+// a comma-separated list and this prefix is never registered with
+// upgrader.Subprotocols.
+const resumeProtocolPrefix = "keyboard.resume."
+
+// resumeTokenFromRequest scans the Sec-WebSocket-Protocol header for a
+// resume token, returning the session ID and last event ID the client
+// has already seen.
+func resumeTokenFromRequest(r *http.Request) (sessionID string, lastEventID uint64, ok bool) {
+	header := r.Header.Get("Sec-WebSocket-Protocol")
+	for _, raw := range strings.Split(header, ",") {
+		token := strings.TrimSpace(raw)
+		rest, found := strings.CutPrefix(token, resumeProtocolPrefix)
+		if !found {
+			continue
+		}
+		idx := strings.LastIndex(rest, ".")
+		if idx < 0 {
+			continue
+		}
+		id, err := strconv.ParseUint(rest[idx+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		return rest[:idx], id, true
+	}
+	return "", 0, false
+}
+
+// sessionInfo records enough about a member to let a reconnecting client
+// resume under the same identity: which room and member it was, and when
+// it was last seen, so sessions older than the server's ResumeTTL can be
+// swept.
+type sessionInfo struct {
+	room     string
+	memberID string
+	name     string
+	role     Role
+	subject  string
+	lastSeen time.Time
+}
+
+// newSessionID mints an unguessable session ID: resumeTokenFromRequest
+// trusts whatever ID a client presents, so a predictable (e.g.
+// sequential) ID would let one client resume another's session by
+// guessing it. 16 random bytes gives an attacker nothing to enumerate
+// within a session's ResumeTTL window.
+func newSessionID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic("server: failed to read random session ID: " + err.Error())
+	}
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSessionIDIsUniqueAndUnguessable(t *testing.T) {
+	a := newSessionID()
+	b := newSessionID()
+
+	if a == b {
+		t.Fatalf("newSessionID returned the same ID twice: %q", a)
+	}
+	if len(a) < 32 {
+		t.Errorf("newSessionID() = %q, want at least 32 hex chars of entropy", a)
+	}
+}
+
+func TestResumeTokenFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/ws/room", nil)
+	r.Header.Set("Sec-WebSocket-Protocol", "keyboard.v2.bin, keyboard.resume.abc123.42")
+
+	sessionID, lastEventID, ok := resumeTokenFromRequest(r)
+	if !ok || sessionID != "abc123" || lastEventID != 42 {
+		t.Errorf("resumeTokenFromRequest = %q, %d, %v, want abc123, 42, true", sessionID, lastEventID, ok)
+	}
+}
+
+func TestResumeTokenFromRequestAbsent(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/ws/room", nil)
+	r.Header.Set("Sec-WebSocket-Protocol", "keyboard.v2.bin")
+
+	if _, _, ok := resumeTokenFromRequest(r); ok {
+		t.Error("resumeTokenFromRequest found a resume token that wasn't offered")
+	}
+}
@@ -1,123 +1,577 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"keyboard/auth"
 	"keyboard/input"
+	"keyboard/macro"
+	"keyboard/observability"
+	"keyboard/protocol"
+
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
+// upgrader negotiates protocol.Subprotocol (compact binary framing) in
+// preference to protocol.LegacySubprotocol (the original JSON framing).
+// A client that offers neither (i.e. doesn't set Sec-WebSocket-Protocol
+// at all) falls back to the JSON framing for backward compatibility.
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
-	HandshakeTimeout: 30 * time.Second,
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	HandshakeTimeout:  30 * time.Second,
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
 	EnableCompression: true,
+	Subprotocols:      []string{protocol.Subprotocol, protocol.LegacySubprotocol},
 }
 
+// KeyMessage is a single frame received from a client over the WebSocket.
+// Type selects how the message is interpreted:
+//   - "key": a key event. Kind is one of "keytap" (default), "keydown",
+//     "keyup", or "type"; Mods holds modifier names for a combo tap, and
+//     Text holds the string to type for "type".
+//   - "control": a moderator control-transfer request (Action is
+//     "grant" or "revoke", Target is the member ID).
+//   - "macro_register": registers Events under Name for later replay.
+//   - "macro": replays the events registered under Name.
+//   - "mouse": a pointer event. MouseKind is one of "move" (default),
+//     "click", "scroll", or "drag"; X/Y (or DX/DY for "scroll") carry the
+//     coordinates, Absolute says whether X/Y are screen-absolute or a
+//     relative offset, Button and Double apply to "click", and From/To
+//     apply to "drag". Requires mouse control to be enabled server-side
+//     and the sender to hold control of the room.
+//   - "screenInfo": requests the host's display size; answered with a
+//     "screenInfo" response carrying its width and height.
 type KeyMessage struct {
-	Key  string `json:"key"`
 	Type string `json:"type"`
+
+	Kind string   `json:"kind,omitempty"`
+	Key  string   `json:"key,omitempty"`
+	Mods []string `json:"mods,omitempty"`
+	Text string   `json:"text,omitempty"`
+
+	Action string `json:"action,omitempty"`
+	Target string `json:"target,omitempty"`
+
+	Name   string        `json:"name,omitempty"`
+	Events []macro.Event `json:"events,omitempty"`
+
+	MouseKind string       `json:"mouseKind,omitempty"`
+	X         int          `json:"x,omitempty"`
+	Y         int          `json:"y,omitempty"`
+	Absolute  bool         `json:"absolute,omitempty"`
+	Button    string       `json:"button,omitempty"`
+	Double    bool         `json:"double,omitempty"`
+	DX        int          `json:"dx,omitempty"`
+	DY        int          `json:"dy,omitempty"`
+	From      *input.Point `json:"from,omitempty"`
+	To        *input.Point `json:"to,omitempty"`
+}
+
+// screenInfoMessage answers a "screenInfo" request with the host's
+// display resolution, so a client can translate normalized trackpad
+// coordinates into absolute ones.
+type screenInfoMessage struct {
+	Type   string `json:"type"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// wsMessage pairs a payload with the WebSocket frame type it must be
+// written as, so writePump stays the only goroutine that ever writes to
+// the connection.
+type wsMessage struct {
+	kind int
+	data []byte
 }
 
 type Connection struct {
-	conn *websocket.Conn
-	send chan []byte
+	conn   *websocket.Conn
+	send   chan wsMessage
+	binary bool
+
+	// pingSentAt is the UnixNano timestamp of the last keepalive ping,
+	// read back in the pong handler to compute RTT. Written by writePump
+	// and read by the pong handler, so it's accessed atomically rather
+	// than guarded by a mutex.
+	pingSentAt int64
+}
+
+// sendText queues a JSON control message, blocking if the send buffer is
+// full.
+func (c *Connection) sendText(data []byte) {
+	c.send <- wsMessage{kind: websocket.TextMessage, data: data}
+}
+
+// sendBinary queues a binary protocol.Frame batch, blocking if the send
+// buffer is full.
+func (c *Connection) sendBinary(data []byte) {
+	c.send <- wsMessage{kind: websocket.BinaryMessage, data: data}
+}
+
+// trySendText queues a JSON message without blocking, dropping it if the
+// send buffer is full. Used for broadcasts, where one slow reader
+// shouldn't stall the rest of the room.
+func (c *Connection) trySendText(data []byte) bool {
+	select {
+	case c.send <- wsMessage{kind: websocket.TextMessage, data: data}:
+		return true
+	default:
+		return false
+	}
+}
+
+var nextMemberID uint64
+
+func newMemberID() string {
+	return fmt.Sprintf("m%d", atomic.AddUint64(&nextMemberID, 1))
 }
 
+// WebSocketServer accepts WebSocket upgrades and hands connected clients
+// off to a RoomManager, which owns room membership, roles, and key
+// dispatch.
 type WebSocketServer struct {
-	connections map[*Connection]bool
-	register    chan *Connection
-	unregister  chan *Connection
-	broadcast   chan []byte
+	rooms   *RoomManager
+	input   input.KeySimulator
+	mouse   input.MouseSimulator
+	macros  *macro.Registry
+	auth    *auth.Service
+	limiter *auth.RateLimiter
+	logger  *slog.Logger
+	metrics *observability.Metrics
+
 	mutex       sync.Mutex
-	input       input.KeySimulator
+	connections map[*Connection]bool
+	sessions    map[string]*sessionInfo
+	resumeTTL   time.Duration
 }
 
-func NewWebSocketServer() *WebSocketServer {
+// defaultResumeTTL is used when the caller never calls SetResumeTTL.
+const defaultResumeTTL = 2 * time.Minute
+
+// NewWebSocketServer creates a WebSocketServer backed by a RoomManager
+// configured with cfg. Call Run to start processing room events.
+func NewWebSocketServer(cfg RoomConfig) *WebSocketServer {
 	return &WebSocketServer{
+		rooms:       NewRoomManager(cfg),
 		connections: make(map[*Connection]bool),
-		register:    make(chan *Connection),
-		unregister:  make(chan *Connection),
-		broadcast:   make(chan []byte),
+		sessions:    make(map[string]*sessionInfo),
+		resumeTTL:   defaultResumeTTL,
 	}
 }
 
+// SetResumeTTL controls how long a disconnected session remains
+// resumable before it is swept from the session index.
+func (s *WebSocketServer) SetResumeTTL(ttl time.Duration) {
+	s.resumeTTL = ttl
+}
+
+// SetMacroRegistry wires the macro registry used to persist and replay
+// "macro_register"/"macro" messages. Without one, those message types are
+// ignored.
+func (s *WebSocketServer) SetMacroRegistry(registry *macro.Registry) {
+	s.macros = registry
+}
+
+// SetAuthenticator wires the auth.Service that gates WebSocket upgrades
+// and exposes the /auth and /auth/qr endpoints. Without one (or with one
+// whose Enabled method reports false), connections are unauthenticated.
+func (s *WebSocketServer) SetAuthenticator(service *auth.Service) {
+	s.auth = service
+}
+
+// SetRateLimiter wires a per-member rate limiter around key dispatch.
+// Without one, key events are not rate limited.
+func (s *WebSocketServer) SetRateLimiter(limiter *auth.RateLimiter) {
+	s.limiter = limiter
+}
+
+// SetMouseSimulator wires the MouseSimulator used to handle "mouse"
+// messages. Without one, mouse control is disabled and such messages are
+// ignored.
+func (s *WebSocketServer) SetMouseSimulator(simulator input.MouseSimulator) {
+	s.mouse = simulator
+}
+
+// SetLogger wires a structured logger, shared with the RoomManager.
+// Without one, slog.Default() is used.
+func (s *WebSocketServer) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+	s.rooms.SetLogger(logger)
+}
+
+// SetMetrics wires the Prometheus collectors exposed on /metrics, shared
+// with the RoomManager. Without one, nothing is recorded.
+func (s *WebSocketServer) SetMetrics(metrics *observability.Metrics) {
+	s.metrics = metrics
+	s.rooms.SetMetrics(metrics)
+}
+
+// log returns the server's logger, falling back to slog.Default() so
+// logging is never silently dropped just because SetLogger wasn't
+// called.
+func (s *WebSocketServer) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	return slog.Default()
+}
+
 func (s *WebSocketServer) SetupRoutes(router *mux.Router) {
-	router.HandleFunc("/ws", s.handleWebSocket)
+	router.HandleFunc("/ws/{room}", s.handleWebSocket)
+	router.HandleFunc("/rooms", s.handleListRooms).Methods(http.MethodGet)
+	router.HandleFunc("/rooms/{room}/members", s.handleListMembers).Methods(http.MethodGet)
+
+	if s.auth != nil && s.auth.Enabled() {
+		router.HandleFunc("/auth", s.handleAuth).Methods(http.MethodPost)
+		router.HandleFunc("/auth/qr", s.handleAuthQR).Methods(http.MethodGet)
+	}
+
+	if s.metrics != nil {
+		router.Handle("/metrics", s.metrics.Handler()).Methods(http.MethodGet)
+	}
+}
+
+func (s *WebSocketServer) handleListRooms(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.rooms.List())
+}
+
+func (s *WebSocketServer) handleListMembers(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["room"]
+	members, ok := s.rooms.Members(roomName)
+	if !ok {
+		http.Error(w, "room not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
 }
 
 func (s *WebSocketServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	roomName := mux.Vars(r)["room"]
+
+	var subject string
+	if s.auth != nil && s.auth.Enabled() {
+		var err error
+		subject, err = s.auth.Authenticate(r)
+		if err != nil {
+			s.metrics.ConnectionRejected("auth")
+			http.Error(w, err.Error(), auth.StatusCode(err))
+			return
+		}
+	}
+
+	resumeID, lastEventID, resuming := resumeTokenFromRequest(r)
+	var resumed *sessionInfo
+	if resuming {
+		s.mutex.Lock()
+		// info.subject == subject stops one authenticated subject (or,
+		// under AuthMode=none, any client) from resuming a session that
+		// was freshly authenticated as someone else — the session ID
+		// alone isn't proof of ownership, just a lookup key.
+		if info, ok := s.sessions[resumeID]; ok && info.room == roomName && info.subject == subject && time.Since(info.lastSeen) <= s.resumeTTL {
+			resumed = info
+		}
+		s.mutex.Unlock()
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		s.metrics.ConnectionRejected("upgrade")
+		s.log().Error("WebSocket upgrade error", "error", err)
 		return
 	}
 
 	conn.SetReadLimit(512)
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+
+	connection := &Connection{
+		conn:   conn,
+		send:   make(chan wsMessage, 256),
+		binary: conn.Subprotocol() == protocol.Subprotocol,
+	}
+
 	conn.SetPongHandler(func(string) error {
 		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		if sentAt := atomic.LoadInt64(&connection.pingSentAt); sentAt != 0 {
+			s.metrics.ObservePingRTT(time.Since(time.Unix(0, sentAt)))
+		}
 		return nil
 	})
 
-	connection := &Connection{
-		conn: conn,
-		send: make(chan []byte, 256),
+	var sessionID string
+	var member *Member
+	if resumed != nil {
+		sessionID = resumeID
+		member = &Member{ID: resumed.memberID, Name: resumed.name, Role: resumed.role, Subject: resumed.subject, conn: connection}
+	} else {
+		sessionID = newSessionID()
+		role := RoleObserver
+		if token := r.URL.Query().Get("moderator_token"); token != "" && s.rooms.cfg.ModeratorToken != "" && token == s.rooms.cfg.ModeratorToken {
+			role = RoleModerator
+		}
+		member = &Member{
+			ID:      newMemberID(),
+			Name:    r.URL.Query().Get("name"),
+			Role:    role,
+			Subject: subject,
+			conn:    connection,
+		}
+	}
+
+	result := make(chan error, 1)
+	s.rooms.join <- &joinRequest{room: roomName, member: member, result: result}
+	if err := <-result; err != nil {
+		s.metrics.ConnectionRejected("room_full")
+		errorMsg, _ := json.Marshal(map[string]string{"error": err.Error()})
+		conn.WriteMessage(websocket.TextMessage, errorMsg)
+		conn.Close()
+		return
 	}
+	s.metrics.ConnectionAccepted()
+
+	s.mutex.Lock()
+	s.connections[connection] = true
+	s.sessions[sessionID] = &sessionInfo{
+		room:     roomName,
+		memberID: member.ID,
+		name:     member.Name,
+		role:     member.Role,
+		subject:  member.Subject,
+		lastSeen: time.Now(),
+	}
+	s.mutex.Unlock()
 
-	s.register <- connection
+	welcomeMsg, _ := json.Marshal(map[string]interface{}{
+		"status":    "connected",
+		"id":        member.ID,
+		"role":      string(member.Role),
+		"sessionId": sessionID,
+	})
+	connection.sendText(welcomeMsg)
+
+	if resumed != nil {
+		for _, evt := range s.rooms.EventsSince(roomName, lastEventID, member.ID) {
+			connection.sendText(evt.data)
+		}
+	}
 
 	go s.writePump(connection)
-	go s.readPump(connection)
+	go s.readPump(connection, sessionID, roomName, member)
 }
 
-func (s *WebSocketServer) readPump(connection *Connection) {
+func (s *WebSocketServer) readPump(connection *Connection, sessionID, roomName string, member *Member) {
 	defer func() {
-		s.unregister <- connection
+		s.rooms.leave <- &leaveRequest{room: roomName, memberID: member.ID}
+
+		s.mutex.Lock()
+		delete(s.connections, connection)
+		if info, ok := s.sessions[sessionID]; ok {
+			info.lastSeen = time.Now()
+		}
+		s.mutex.Unlock()
+
 		connection.conn.Close()
 	}()
 
 	for {
 		connection.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		_, message, err := connection.conn.ReadMessage()
+		wsType, message, err := connection.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket read error: %v", err)
+				s.log().Error("WebSocket read error", "error", err)
 			}
 			break
 		}
 
-		log.Printf("Received message: %s", string(message))
+		if connection.binary && wsType == websocket.BinaryMessage {
+			s.handleBinaryMessage(connection, roomName, sessionID, member, message)
+			continue
+		}
 
 		var keyMsg KeyMessage
 		if err := json.Unmarshal(message, &keyMsg); err != nil {
-			log.Printf("JSON parse error: %v", err)
+			s.log().Error("JSON parse error", "error", err)
 			continue
 		}
 
-		if s.input != nil && keyMsg.Type == "key" {
-			log.Printf("Key pressed: %s", keyMsg.Key)
-			if err := s.input.PressKey(keyMsg.Key); err != nil {
-				log.Printf("Key press error: %v", err)
-				errorMsg, _ := json.Marshal(map[string]string{
-					"error": "Failed to press key: " + keyMsg.Key,
-				})
-				connection.send <- errorMsg
-			} else {
-				log.Printf("Key successfully pressed: %s", keyMsg.Key)
+		switch keyMsg.Type {
+		case "key":
+			kind := input.EventKind(keyMsg.Kind)
+			if kind == "" {
+				kind = input.EventKeyTap
 			}
+			event := input.Event{Kind: kind, Key: keyMsg.Key, Mods: keyMsg.Mods, Text: keyMsg.Text}
+			s.dispatchKeyEvent(connection, roomName, sessionID, member, event)
+
+		case "control":
+			s.rooms.control <- &controlRequest{room: roomName, actor: member, action: keyMsg.Action, target: keyMsg.Target}
+
+		case "macro_register":
+			s.handleMacroRegister(roomName, member, keyMsg)
+
+		case "macro":
+			s.handleMacroTrigger(roomName, member, keyMsg.Name)
+
+		case "mouse":
+			s.handleMouse(connection, roomName, member, keyMsg)
+
+		case "screenInfo":
+			s.handleScreenInfo(connection)
+		}
+	}
+}
+
+// handleBinaryMessage decodes a batch of protocol.Frame coalesced into a
+// single binary WebSocket message and dispatches each in order, so a
+// client typing quickly (or holding a repeating key) pays for one read
+// instead of one per keystroke.
+func (s *WebSocketServer) handleBinaryMessage(connection *Connection, roomName, sessionID string, member *Member, message []byte) {
+	frames, err := (protocol.BinaryCodec{}).Decode(message)
+	if err != nil {
+		s.log().Error("Binary frame decode error", "error", err)
+		return
+	}
+
+	for _, frame := range frames {
+		switch frame.Opcode {
+		case protocol.OpPing:
+			ack, _ := (protocol.BinaryCodec{}).Encode([]protocol.Frame{{Opcode: protocol.OpAck}})
+			connection.sendBinary(ack)
+		case protocol.OpAck:
+			// Acknowledgment of a prior server frame; nothing to do.
+		default:
+			s.dispatchKeyEvent(connection, roomName, sessionID, member, frame.Event())
 		}
 	}
 }
 
+// dispatchKeyEvent rate-limits and forwards a single key event to the
+// RoomManager, regardless of which wire format it arrived in. It opens
+// the keystroke's trace span and stamps its receive time, both of which
+// travel with the event so the RoomManager can close out the span and
+// record latency once the simulator has actually acted on it.
+func (s *WebSocketServer) dispatchKeyEvent(connection *Connection, roomName, sessionID string, member *Member, event input.Event) {
+	if s.limiter != nil && !s.limiter.Allow(member.ID) {
+		errorMsg, _ := json.Marshal(map[string]string{"error": "rate limit exceeded"})
+		connection.sendText(errorMsg)
+		return
+	}
+	s.metrics.KeyEvent(metricKeyLabel(event.Key))
+	_, span := observability.StartKeystrokeSpan(context.Background(), roomName, sessionID)
+	s.rooms.action <- &actionEvent{room: roomName, actor: member, event: event, receivedAt: time.Now(), span: span}
+}
+
+// metricKeyLabel bounds the label cardinality of the keyboard_key_events_total
+// metric to protocol.KeyID's fixed vocabulary, so a client can't inflate it
+// with arbitrary unique strings (the raw, client-supplied key never reaches
+// a CounterVec label).
+func metricKeyLabel(key string) string {
+	if name := protocol.KeyIDForName(key).Name(); name != "" {
+		return name
+	}
+	return "other"
+}
+
+// handleMacroRegister persists the macro and acknowledges it through the
+// room's event log (rather than a direct connection.sendText), so the
+// ack survives a brief reconnect instead of being silently lost. Gated
+// on the same IsController check as handleMacroTrigger: the registry is
+// a single store shared across every room, so an unprivileged member in
+// one room could otherwise clobber a macro name another room depends on.
+func (s *WebSocketServer) handleMacroRegister(roomName string, member *Member, keyMsg KeyMessage) {
+	if s.macros == nil {
+		return
+	}
+	if !s.rooms.IsController(roomName, member) {
+		return
+	}
+	if err := s.macros.Register(macro.Macro{Name: keyMsg.Name, Events: keyMsg.Events}); err != nil {
+		s.log().Error("Macro register error", "error", err)
+		return
+	}
+	ack, _ := json.Marshal(map[string]string{"status": "macro_registered", "name": keyMsg.Name})
+	s.rooms.LogAndDeliver(roomName, member.ID, ack)
+}
+
+func (s *WebSocketServer) handleMacroTrigger(roomName string, member *Member, name string) {
+	if s.macros == nil {
+		return
+	}
+	if !s.rooms.IsController(roomName, member) {
+		return
+	}
+	if err := s.macros.Trigger(name, s.input); err != nil {
+		s.log().Error("Macro trigger error", "error", err)
+		errorMsg, _ := json.Marshal(map[string]string{"error": "Failed to trigger macro: " + name})
+		s.rooms.LogAndDeliver(roomName, member.ID, errorMsg)
+	}
+}
+
+// handleMouse converts a "mouse" message into an input.MouseEvent and
+// forwards it to the RoomManager, which enforces that only the room's
+// controller may move the pointer. Ignored entirely if no MouseSimulator
+// was wired, so mouse control can be disabled independently of keyboard
+// control.
+func (s *WebSocketServer) handleMouse(connection *Connection, roomName string, member *Member, keyMsg KeyMessage) {
+	if s.mouse == nil {
+		return
+	}
+
+	event := input.MouseEvent{
+		Kind:     input.MouseEventKind(keyMsg.MouseKind),
+		X:        keyMsg.X,
+		Y:        keyMsg.Y,
+		Absolute: keyMsg.Absolute,
+		Button:   keyMsg.Button,
+		Double:   keyMsg.Double,
+		DX:       keyMsg.DX,
+		DY:       keyMsg.DY,
+	}
+	if event.Kind == "" {
+		event.Kind = input.MouseMove
+	}
+	if keyMsg.From != nil {
+		event.From = *keyMsg.From
+	}
+	if keyMsg.To != nil {
+		event.To = *keyMsg.To
+	}
+
+	s.rooms.mouse <- &mouseActionEvent{room: roomName, actor: member, event: event}
+}
+
+// handleScreenInfo answers a "screenInfo" request with the host's
+// display resolution, or an error if mouse control isn't enabled.
+func (s *WebSocketServer) handleScreenInfo(connection *Connection) {
+	if s.mouse == nil {
+		errorMsg, _ := json.Marshal(map[string]string{"error": "mouse control is disabled"})
+		connection.sendText(errorMsg)
+		return
+	}
+
+	size, err := s.mouse.ScreenSize()
+	if err != nil {
+		errorMsg, _ := json.Marshal(map[string]string{"error": "Failed to read screen size"})
+		connection.sendText(errorMsg)
+		return
+	}
+
+	payload, _ := json.Marshal(screenInfoMessage{Type: "screenInfo", Width: size.X, Height: size.Y})
+	connection.sendText(payload)
+}
+
 func (s *WebSocketServer) writePump(connection *Connection) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer func() {
@@ -133,13 +587,14 @@ func (s *WebSocketServer) writePump(connection *Connection) {
 				return
 			}
 
-			if err := connection.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("WebSocket write error: %v", err)
+			if err := connection.conn.WriteMessage(message.kind, message.data); err != nil {
+				s.log().Error("WebSocket write error", "error", err)
 				return
 			}
 
 		case <-ticker.C:
 			connection.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			atomic.StoreInt64(&connection.pingSentAt, time.Now().UnixNano())
 			if err := connection.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -147,69 +602,37 @@ func (s *WebSocketServer) writePump(connection *Connection) {
 	}
 }
 
+// Run starts the RoomManager's event loop. It blocks until the process
+// exits; call it in its own goroutine.
 func (s *WebSocketServer) Run() {
-	for {
-		select {
-		case connection := <-s.register:
-			s.mutex.Lock()
-			if len(s.connections) >= 1 {
-				existingConn := s.getExistingConnection()
-				if existingConn != nil {
-					closeMsg, _ := json.Marshal(map[string]string{
-						"status": "disconnected",
-						"reason": "Another device connected",
-					})
-					select {
-					case existingConn.send <- closeMsg:
-						// Wait a bit for the message to be sent
-						time.Sleep(100 * time.Millisecond)
-					default:
-						// Channel full, connection closing
-					}
-					existingConn.conn.Close()
-				}
-			}
-			s.connections[connection] = true
-			welcomeMsg, _ := json.Marshal(map[string]string{
-				"status": "connected",
-			})
-			connection.send <- welcomeMsg
-			s.mutex.Unlock()
-			log.Printf("New WebSocket connection. Total connections: %d", len(s.connections))
-
-		case connection := <-s.unregister:
-			s.mutex.Lock()
-			if _, ok := s.connections[connection]; ok {
-				delete(s.connections, connection)
-				close(connection.send)
-			}
-			s.mutex.Unlock()
-			log.Printf("WebSocket disconnected. Total connections: %d", len(s.connections))
-
-		case message := <-s.broadcast:
-			s.mutex.Lock()
-			for connection := range s.connections {
-				select {
-				case connection.send <- message:
-				default:
-					close(connection.send)
-					delete(s.connections, connection)
-				}
-			}
-			s.mutex.Unlock()
-		}
-	}
+	go s.sweepSessions()
+	s.rooms.Run(s.input, s.mouse)
 }
 
-func (s *WebSocketServer) getExistingConnection() *Connection {
-	for conn := range s.connections {
-		return conn
+// sweepSessions periodically evicts sessions that have outlived
+// ResumeTTL, so a client that never comes back doesn't pin its session
+// index entry (and the room event log behind it) forever.
+func (s *WebSocketServer) sweepSessions() {
+	interval := s.resumeTTL
+	if interval <= 0 {
+		interval = defaultResumeTTL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mutex.Lock()
+		for id, info := range s.sessions {
+			if time.Since(info.lastSeen) > s.resumeTTL {
+				delete(s.sessions, id)
+			}
+		}
+		s.mutex.Unlock()
 	}
-	return nil
 }
 
-func (s *WebSocketServer) SetInputSimulator(input input.KeySimulator) {
-	s.input = input
+func (s *WebSocketServer) SetInputSimulator(simulator input.KeySimulator) {
+	s.input = simulator
 }
 
 func (s *WebSocketServer) Shutdown() {
@@ -0,0 +1,19 @@
+package server
+
+import "testing"
+
+func TestMetricKeyLabel(t *testing.T) {
+	cases := map[string]string{
+		"Enter":                     "enter",
+		"a":                         "a",
+		"ARROWUP":                   "arrowup",
+		"<script>alert(1)</script>": "other",
+		"":                          "other",
+	}
+
+	for key, want := range cases {
+		if got := metricKeyLabel(key); got != want {
+			t.Errorf("metricKeyLabel(%q) = %q, want %q", key, got, want)
+		}
+	}
+}